@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cast"
 	"github.com/ssyrota/frog-db/src/core/db"
+	"github.com/ssyrota/frog-db/src/core/db/codec"
 	"github.com/ssyrota/frog-db/src/web"
 	"github.com/tj/go/env"
 )
@@ -23,12 +28,96 @@ func load() error {
 	if err != nil {
 		return fmt.Errorf("parse dump ivl: %w", err)
 	}
-	db, err := db.New(dumpPath, dumpInterval)
+	dumpFormat, err := db.ParseDumpFormat(env.GetDefault("DUMP_FORMAT", string(db.DumpJson)))
+	if err != nil {
+		return fmt.Errorf("parse dump format: %w", err)
+	}
+	dumpCodec, err := codec.Get(env.GetDefault("DUMP_CODEC", codec.None.Name()))
+	if err != nil {
+		return fmt.Errorf("parse dump codec: %w", err)
+	}
+	dumpKeep, err := cast.ToIntE(env.GetDefault("DUMP_KEEP", "1"))
+	if err != nil {
+		return fmt.Errorf("parse dump keep: %w", err)
+	}
+	dumpKeepFor, err := time.ParseDuration(env.GetDefault("DUMP_KEEP_FOR", "0s"))
+	if err != nil {
+		return fmt.Errorf("parse dump keep for: %w", err)
+	}
+	shutdownTimeout, err := time.ParseDuration(env.GetDefault("SHUTDOWN_TIMEOUT", "10s"))
+	if err != nil {
+		return fmt.Errorf("parse shutdown timeout: %w", err)
+	}
+	importBatchSize, err := cast.ToIntE(env.GetDefault("IMPORT_BATCH_SIZE", "1000"))
+	if err != nil {
+		return fmt.Errorf("parse import batch size: %w", err)
+	}
+	gcInterval, err := time.ParseDuration(env.GetDefault("GC_IVL", "1m"))
+	if err != nil {
+		return fmt.Errorf("parse gc ivl: %w", err)
+	}
+	requestTimeout, err := time.ParseDuration(env.GetDefault("REQUEST_TIMEOUT", "30s"))
+	if err != nil {
+		return fmt.Errorf("parse request timeout: %w", err)
+	}
+	db, err := db.New(context.Background(), dumpPath, dumpInterval,
+		db.WithDumpFormat(dumpFormat),
+		db.WithDumpCodec(dumpCodec),
+		db.WithDumpRetention(db.Retention{Count: dumpKeep, MaxAge: dumpKeepFor}),
+		db.WithImportBatchSize(importBatchSize),
+		db.WithGcInterval(gcInterval),
+		db.WithLoadProgress(logLoadProgress()))
 	if err != nil {
 		return fmt.Errorf("init db: %w", err)
 	}
-	if err := web.New(db, uint16(port)).Run(); err != nil {
+	dbSchema, err := db.IntrospectSchema()
+	if err != nil {
+		return fmt.Errorf("introspect db: %w", err)
+	}
+	log.Printf("recovered %d tables from %s", len(dbSchema), dumpPath)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	server := web.New(db, uint16(port), web.WithRequestTimeout(requestTimeout))
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Run()
+	}()
+	select {
+	case err := <-serverErr:
+		if closeErr := db.Close(); closeErr != nil {
+			log.Printf("close db: %s", closeErr.Error())
+		}
 		return fmt.Errorf("run rest: %w", err)
+	case sig := <-signals:
+		log.Printf("received %s, shutting down", sig)
 	}
+	// Second signal kills process right away
+	signal.Stop(signals)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	shutdownErr := server.Shutdown(ctx)
+	if err := <-serverErr; err != nil && shutdownErr == nil {
+		shutdownErr = err
+	}
+	// Db is closed even if some requests were dropped, final dump matters most
+	if err := db.Close(); err != nil {
+		return fmt.Errorf("final dump: %w", err)
+	}
+	if shutdownErr != nil {
+		return fmt.Errorf("shutdown rest: %w", shutdownErr)
+	}
+	log.Printf("stored final dump to %s", dumpPath)
 	return nil
 }
+
+// Log progress of dump loading once per table
+func logLoadProgress() func(db.LoadProgress) {
+	tables := 0
+	return func(progress db.LoadProgress) {
+		if progress.Tables != tables {
+			tables = progress.Tables
+			log.Printf("loaded %d tables, %d rows", progress.Tables, progress.Rows)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Write file atomically: data goes to a temporary file in the same directory,
+// which is fsync'd and renamed over the target, then the directory is fsync'd.
+// Readers see either old or new content, but never a partially written file.
+func writeFileAtomic(path string, write func(w io.Writer) error) (err error) {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, tmpPattern(path))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if err = tmp.Chmod(0644); err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(tmp)
+	if err = write(writer); err != nil {
+		return err
+	}
+	if err = writer.Flush(); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// Remove temporary files left by writes interrupted with crash
+func removeStaleTmp(path string) error {
+	stale, err := filepath.Glob(filepath.Join(filepath.Dir(path), tmpPattern(path)))
+	if err != nil {
+		return err
+	}
+	for _, name := range stale {
+		if err := os.Remove(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tmpPattern(path string) string {
+	return filepath.Base(path) + ".tmp-*"
+}
+
+// Flush directory entry changes (create, rename) to stable storage
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
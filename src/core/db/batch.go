@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ssyrota/frog-db/src/core/db/table"
+	errs "github.com/ssyrota/frog-db/src/core/err"
+)
+
+// Times batch is executed again, when it conflicts with other changes
+const maxBatchRetries = 3
+
+// ExecuteBatch implementation.
+func (db *Database) ExecuteBatch(commands []any) ([]*[]table.ColumnSet, error) {
+	return db.ExecuteBatchContext(context.Background(), commands)
+}
+
+// ExecuteBatchContext implementation.
+// Commands are executed in order within transaction, so either all of
+// them are applied or none. Error of failed command has its index.
+// Nothing is applied, if ctx is done before commit.
+func (db *Database) ExecuteBatchContext(ctx context.Context, commands []any) ([]*[]table.ColumnSet, error) {
+	for attempt := 0; ; attempt++ {
+		results, err := db.executeBatch(ctx, commands)
+		var conflict *errs.ErrTxConflict
+		if errors.As(err, &conflict) && attempt < maxBatchRetries {
+			continue
+		}
+		return results, err
+	}
+}
+
+func (db *Database) executeBatch(ctx context.Context, commands []any) ([]*[]table.ColumnSet, error) {
+	tx := db.Begin()
+	results := make([]*[]table.ColumnSet, len(commands))
+	for i, command := range commands {
+		res, err := tx.ExecuteContext(ctx, command)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("command %d: %w", i, err)
+		}
+		results[i] = res
+	}
+	if err := ctx.Err(); err != nil {
+		tx.Rollback()
+		return nil, contextErr(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,115 @@
+// Package codec provides compression codecs for dumps.
+//
+// Codecs are looked up by name for configuration and detected by magic
+// bytes of the stream on read, so dumps written with any registered codec
+// can be loaded regardless of current configuration.
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+type Codec interface {
+	// Name of codec in configuration
+	Name() string
+	// Bytes every encoded stream starts with, empty for uncompressed data
+	Magic() []byte
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	None Codec = noneCodec{}
+	Gzip Codec = gzipCodec{}
+)
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Codec{}
+)
+
+func init() {
+	Register(None)
+	Register(Gzip)
+}
+
+// Register codec, codec with the same name is replaced
+func Register(c Codec) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[c.Name()] = c
+}
+
+// Get registered codec by name
+func Get(name string) (Codec, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := registry[name]
+	if !ok {
+		names := make([]string, 0, len(registry))
+		for k := range registry {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown codec %s, available: %v", name, names)
+	}
+	return c, nil
+}
+
+// Detect codec by header of stream, None if stream is not compressed
+func Detect(header []byte) Codec {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, c := range registry {
+		if len(c.Magic()) != 0 && bytes.HasPrefix(header, c.Magic()) {
+			return c
+		}
+	}
+	return None
+}
+
+// Length of header required to detect any registered codec
+func MagicLen() int {
+	mu.RLock()
+	defer mu.RUnlock()
+	length := 0
+	for _, c := range registry {
+		if len(c.Magic()) > length {
+			length = len(c.Magic())
+		}
+	}
+	return length
+}
+
+type noneCodec struct{}
+
+func (noneCodec) Name() string  { return "none" }
+func (noneCodec) Magic() []byte { return nil }
+func (noneCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return nopWriteCloser{w}, nil
+}
+func (noneCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(r), nil
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string  { return "gzip" }
+func (gzipCodec) Magic() []byte { return []byte{0x1f, 0x8b} }
+func (gzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
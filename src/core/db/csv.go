@@ -0,0 +1,135 @@
+package db
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	dbtypes "github.com/ssyrota/frog-db/src/core/db/dbtypes"
+	"github.com/ssyrota/frog-db/src/core/db/schema"
+	"github.com/ssyrota/frog-db/src/core/db/table"
+	errs "github.com/ssyrota/frog-db/src/core/err"
+	"golang.org/x/exp/slices"
+)
+
+// Write table as csv with sorted column names in header.
+// Csv reflects state of table at the moment of call.
+func (db *Database) ExportCsv(tableName string, w io.Writer) error {
+	dump, err := db.dumpTable(tableName)
+	if err != nil {
+		return err
+	}
+	defer dump.Release()
+	columns := sortedColumns(dump.Schema)
+	out := csv.NewWriter(w)
+	if err := out.Write(columns); err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	err = dump.Scan(func(row table.ColumnSet) error {
+		for i, column := range columns {
+			text, err := dbtypes.FormatText(dump.Schema[column], row[column])
+			if err != nil {
+				return fmt.Errorf("column %s: %w", column, err)
+			}
+			record[i] = text
+		}
+		return out.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// Import csv with header of table columns in any order. Rows are inserted
+// in batches, invalid lines are skipped and reported, so import is not
+// atomic: rows inserted before failure stay in table.
+func (db *Database) ImportCsv(tableName string, r io.Reader) (*ImportResult, error) {
+	t, err := db.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+	tableSchema := t.Schema()
+	imp := db.newImporter(tableName)
+	in := csv.NewReader(r)
+	header, err := in.Read()
+	if err == io.EOF {
+		return imp.result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCsvHeader(tableSchema, header); err != nil {
+		return nil, err
+	}
+	in.ReuseRecord = true
+	for {
+		record, err := in.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			imp.skip(parseErr.StartLine, parseErr.Err)
+			continue
+		}
+		if err != nil {
+			return imp.result, err
+		}
+		line, _ := in.FieldPos(0)
+		row, err := parseCsvRow(tableSchema, header, record)
+		if err != nil {
+			imp.skip(line, err)
+			continue
+		}
+		if err := imp.add(row); err != nil {
+			return imp.result, err
+		}
+	}
+	return imp.result, imp.flush()
+}
+
+// Check that header has every table column exactly once
+func checkCsvHeader(tableSchema schema.T, header []string) error {
+	omitted := []string{}
+	for column := range tableSchema {
+		if !slices.Contains(header, column) {
+			omitted = append(omitted, column)
+		}
+	}
+	if len(omitted) != 0 {
+		sort.Strings(omitted)
+		return errs.NewErrColumnsRequired(omitted)
+	}
+	for i, column := range header {
+		if _, ok := tableSchema[column]; !ok {
+			return errs.NewErrColumnsNotFound([]string{column})
+		}
+		if slices.Index(header, column) != i {
+			return fmt.Errorf("column %s is repeated in header", column)
+		}
+	}
+	return nil
+}
+
+func parseCsvRow(tableSchema schema.T, header []string, record []string) (table.ColumnSet, error) {
+	row := make(table.ColumnSet, len(header))
+	for i, column := range header {
+		value, err := dbtypes.ParseText(tableSchema[column], record[i])
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", column, err)
+		}
+		row[column] = value
+	}
+	return row, nil
+}
+
+func sortedColumns(tableSchema schema.T) []string {
+	columns := table.MapKeys(tableSchema)
+	sort.Strings(columns)
+	return columns
+}
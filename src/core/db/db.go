@@ -1,146 +1,282 @@
 package db
 
 import (
-	"bufio"
-	"encoding/json"
+	"context"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dustin/go-humanize/english"
+	"github.com/ssyrota/frog-db/src/core/db/codec"
 	"github.com/ssyrota/frog-db/src/core/db/schema"
+	"github.com/ssyrota/frog-db/src/core/db/supervisor"
 	"github.com/ssyrota/frog-db/src/core/db/table"
+	"github.com/ssyrota/frog-db/src/core/db/wal"
 	errs "github.com/ssyrota/frog-db/src/core/err"
 )
 
 type Dump []table.Dump
 type Db interface {
 	Execute(command any) (*[]table.ColumnSet, error)
+	ExecuteContext(ctx context.Context, command any) (*[]table.ColumnSet, error)
+	ExecuteBatch(commands []any) ([]*[]table.ColumnSet, error)
+	ExecuteBatchContext(ctx context.Context, commands []any) ([]*[]table.ColumnSet, error)
+	Begin() *Tx
 	IntrospectSchema() (map[string]schema.T, error)
 	StoreDump() error
+	StoreDumpContext(ctx context.Context) error
 	JsonDump() <-chan DumpMsg
+	JsonDumpContext(ctx context.Context) <-chan DumpMsg
 	FromDump(dumpPath string) error
+	FromDumpFile(dumpPath string) error
+	ListDumps() ([]DumpInfo, error)
+	DumpStatus() (DumpStatus, error)
+	Close() error
 }
 type Database struct {
 	tables map[string]*table.T
 	path   string
+	wal    *wal.Log
+	// dumpFormat and dumpCodec are used by StoreDump and JsonDump,
+	// FromDump detects them by itself
+	dumpFormat DumpFormat
+	dumpCodec  codec.Codec
+	// Segments of the last stored incremental dump by table name
+	segments   map[string]segment
+	segmentSeq uint64
+	retention  Retention
+	dumpErrors dumpErrors
+	// Sequence of disk engine file names
+	engineSeq       atomic.Uint64
+	importBatchSize int
+	loadProgress    func(LoadProgress)
+	gcInterval      time.Duration
+	// Background jobs of db
+	supervisor *supervisor.Supervisor
+	closeOnce  sync.Once
+	closeErr   error
+	// Locks are acquired in order Tx.mu, dumpMu, writeMu, tablesMu, table.T.mu.
+	//
+	// writeMu serializes mutating commands together with their wal records,
+	// so the order of records in the log matches the order of execution.
+	writeMu sync.Mutex
+	// dumpMu serializes dumps, it is acquired before writeMu
+	dumpMu sync.Mutex
+	// tablesMu guards tables. Tables are changed holding both writeMu and
+	// tablesMu, so holding any of them is enough to read tables.
+	tablesMu sync.RWMutex
 }
 
-func New(path string, dumpInterval time.Duration) (*Database, error) {
-	_, err := os.Create(path)
-	if err != nil {
-		return nil, err
+func init() {
+	gob.Register(&CommandDropTable{})
+	gob.Register(&CommandCreateTable{})
+	gob.Register(&CommandInsert{})
+	gob.Register(&CommandUpdate{})
+	gob.Register(&CommandDelete{})
+	gob.Register(&CommandRemoveDuplicates{})
+	// Nested conditions of logged commands
+	gob.Register(table.ColumnSet{})
+	gob.Register([]table.ColumnSet{})
+	gob.Register([]map[string]any{})
+}
+
+type Option func(db *Database)
+
+// Set format of dumps written by StoreDump, json by default
+func WithDumpFormat(format DumpFormat) Option {
+	return func(db *Database) {
+		db.dumpFormat = format
 	}
-	db := &Database{tables: make(map[string]*table.T), path: path}
-	// Run store dump interval job
-	go func() {
-		ticker := time.NewTicker(dumpInterval)
-		for range ticker.C {
-			err := db.StoreDump()
-			if err != nil {
-				log.Printf("error: %s", err.Error())
-			}
-		}
-	}()
-	return db, nil
 }
 
-var _ Db = new(Database)
+// Set compression of dumps, not compressed by default
+func WithDumpCodec(c codec.Codec) Option {
+	return func(db *Database) {
+		db.dumpCodec = c
+	}
+}
 
-// FromDump implementation.
-func (db *Database) FromDump(dumpPath string) error {
-	// Save dump before delete data
-	err := db.StoreDump()
-	if err != nil {
-		return err
+// Create db, recovering data from existing dump and wal.
+// Corrupted dump is never overwritten, db refuses to start instead.
+// Background jobs run until ctx is done or db is closed.
+func New(ctx context.Context, path string, dumpInterval time.Duration, opts ...Option) (*Database, error) {
+	if err := removeStaleTmp(path); err != nil {
+		return nil, err
+	}
+	// Disk engine files are rebuilt from dump and wal
+	if err := os.RemoveAll(enginesDir(path)); err != nil {
+		return nil, err
 	}
-	dumpRaw, err := os.ReadFile(dumpPath)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	var dump Dump
-	err = json.Unmarshal(dumpRaw, &dump)
+	info, err := file.Stat()
+	file.Close()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	// Clean up tables if exists
-	db.tables = make(map[string]*table.T)
-	for _, dumpTable := range dump {
-		if _, err := db.Execute(&CommandCreateTable{dumpTable.Name, dumpTable.Schema}); err != nil {
-			return err
-		}
-		storedTable, err := db.table(dumpTable.Name)
+	db := &Database{
+		tables:          make(map[string]*table.T),
+		path:            path,
+		dumpFormat:      DumpJson,
+		dumpCodec:       codec.None,
+		segments:        make(map[string]segment),
+		retention:       Retention{Count: 1},
+		importBatchSize: 1000,
+		gcInterval:      time.Minute,
+		// Segment names must not repeat the ones of previous runs
+		segmentSeq: uint64(time.Now().UnixNano()),
+	}
+	for _, opt := range opts {
+		opt(db)
+	}
+	if info.Size() != 0 {
+		tables, recovered, err := db.readDump(path)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("recover dump: %w", err)
 		}
-		if err := storedTable.LoadDump(&dumpTable.Data); err != nil {
-			return err
+		db.tables = tables
+		// Segments of recovered dump are up to date until tables change
+		if recovered != nil {
+			for _, s := range recovered.Segments {
+				t := tables[s.Table]
+				db.segments[s.Table] = segment{t, t.Version(), s.File, s.Checksum}
+			}
 		}
 	}
-	return nil
+	db.wal, err = wal.Open(walPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("open wal: %w", err)
+	}
+	if err := db.replayWal(); err != nil {
+		db.wal.Close()
+		closeTables(db.tables)
+		return nil, fmt.Errorf("replay wal: %w", err)
+	}
+	db.supervisor = supervisor.New(ctx)
+	db.supervisor.Every(dumpJob, dumpInterval, func(ctx context.Context) error {
+		return db.StoreDumpContext(ctx)
+	})
+	db.supervisor.Every(gcJob, db.gcInterval, func(ctx context.Context) error {
+		return db.collectGarbage()
+	})
+	return db, nil
 }
 
-// StoreDump implementation.
-func (db *Database) StoreDump() error {
-	file, err := os.OpenFile(db.path, os.O_RDWR, 0644)
-	defer file.Close()
-	if err != nil {
-		return err
+// Names of jobs, that store dumps and reclaim storage of old rows by interval
+const (
+	dumpJob = "dump"
+	gcJob   = "gc"
+)
+
+// Set how often storage of replaced and deleted rows is reclaimed,
+// every minute by default
+func WithGcInterval(interval time.Duration) Option {
+	return func(db *Database) {
+		db.gcInterval = interval
 	}
-	writer := bufio.NewWriter(file)
-	dumpCh := db.JsonDump()
-	for value := range dumpCh {
-		if value.Err != nil {
-			return err
-		}
-		_, err := writer.Write(value.Payload)
-		if err != nil {
-			return err
+}
+
+// Reclaim storage of replaced and deleted rows of all tables
+func (db *Database) collectGarbage() error {
+	db.tablesMu.RLock()
+	tables := make(map[string]*table.T, len(db.tables))
+	for name, t := range db.tables {
+		tables[name] = t
+	}
+	db.tablesMu.RUnlock()
+	for name, t := range tables {
+		if err := t.Vacuum(); err != nil {
+			return fmt.Errorf("table %s: %w", name, err)
 		}
-		writer.Flush()
 	}
 	return nil
 }
 
-type DumpMsg struct {
-	Payload []byte
-	Err     error
+// Statuses of background jobs
+func (db *Database) Jobs() []supervisor.Status {
+	return db.supervisor.Jobs()
 }
 
-// JsonDump implementation.
-func (db *Database) JsonDump() <-chan DumpMsg {
-	ch := make(chan DumpMsg)
-	go func() {
-		ch <- DumpMsg{[]byte("["), nil}
-		tableNames := table.MapKeys(db.tables)
-		for i, tableName := range tableNames {
-			dump, err := db.tables[tableName].Dump(tableName)
-			if err != nil {
-				ch <- DumpMsg{nil, err}
-				close(ch)
-				return
-			}
-			bytes, err := json.Marshal(dump)
-			if err != nil {
-				ch <- DumpMsg{nil, err}
-				close(ch)
-				return
-			}
-			ch <- DumpMsg{bytes, nil}
-			if i != len(tableNames)-1 {
-				ch <- DumpMsg{[]byte(","), nil}
-			}
+// Stop background jobs, store final dump, close wal and release tables.
+// Wal keeps commands, if final dump fails, so nothing is lost.
+func (db *Database) Close() error {
+	db.closeOnce.Do(func() {
+		db.supervisor.Stop()
+		db.closeErr = db.StoreDump()
+		if err := db.wal.Close(); err != nil && db.closeErr == nil {
+			db.closeErr = err
 		}
+		db.writeMu.Lock()
+		closeTables(db.tables)
+		db.writeMu.Unlock()
+	})
+	return db.closeErr
+}
+
+var _ Db = new(Database)
+
+// Path of write-ahead log, that belongs to dump
+func walPath(dumpPath string) string {
+	return dumpPath + ".wal"
+}
 
-		ch <- DumpMsg{[]byte("]"), nil}
-		close(ch)
-	}()
-	return ch
+// Directory of disk engine files, that belong to dump
+func enginesDir(dumpPath string) string {
+	return dumpPath + ".pages"
+}
+
+// Create table stored by engine of kind
+func (db *Database) newTable(name string, sch schema.T, kind table.EngineKind) (*table.T, error) {
+	kind, err := table.ParseEngineKind(string(kind))
+	if err != nil {
+		return nil, err
+	}
+	if kind == table.MemoryEngine {
+		return table.NewTable(sch)
+	}
+	if err := os.MkdirAll(enginesDir(db.path), 0755); err != nil {
+		return nil, errs.NewErrDbIO(err)
+	}
+	fileName := hex.EncodeToString([]byte(name)) + "-" + strconv.FormatUint(db.engineSeq.Add(1), 10) + ".pages"
+	engine, err := table.NewDiskEngine(filepath.Join(enginesDir(db.path), fileName), sch)
+	if err != nil {
+		return nil, errs.NewErrDbIO(err)
+	}
+	return table.NewTableWithEngine(sch, engine)
+}
+
+// Apply commands, that were acknowledged after the last dump.
+// Commands are validated before they are logged, but still may fail to
+// apply, e.g. on io error. Such commands fail on replay too and are skipped.
+func (db *Database) replayWal() error {
+	return db.wal.Replay(func(entry any) error {
+		if tx, ok := entry.(*CommandTx); ok {
+			if err := db.replayTx(tx); err != nil {
+				log.Printf("wal replay: skip transaction: %s", err.Error())
+			}
+			return nil
+		}
+		if _, err := db.execute(entry); err != nil {
+			log.Printf("wal replay: skip %T: %s", entry, err.Error())
+		}
+		return nil
+	})
 }
 
 // IntrospectSchema implementation.
 func (db *Database) IntrospectSchema() (map[string]schema.T, error) {
+	db.tablesMu.RLock()
+	defer db.tablesMu.RUnlock()
 	dbSchema := map[string]schema.T{}
 	for k, t := range db.tables {
 		dbSchema[k] = t.Schema()
@@ -150,21 +286,107 @@ func (db *Database) IntrospectSchema() (map[string]schema.T, error) {
 
 // Execute implementation.
 func (db *Database) Execute(command any) (*[]table.ColumnSet, error) {
+	return db.ExecuteContext(context.Background(), command)
+}
+
+// ExecuteContext implementation.
+// Mutating commands are validated and appended to the wal before they are
+// applied, so rejected commands are never logged. Command is neither logged
+// nor applied, if ctx is done before its rows are found. Logged command is
+// applied regardless of ctx, because replay of the wal would apply it anyway.
+// Exceeded deadline is reported as ErrTimeout.
+func (db *Database) ExecuteContext(ctx context.Context, command any) (*[]table.ColumnSet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextErr(err)
+	}
+	if !isMutating(command) {
+		return db.executeIn(ctx, db, command)
+	}
+	db.writeMu.Lock()
+	defer db.writeMu.Unlock()
+	// Deadline may pass while waiting for other writers
+	if err := ctx.Err(); err != nil {
+		return nil, contextErr(err)
+	}
+	apply, err := db.prepare(ctx, db, command)
+	if err != nil {
+		return nil, contextErr(err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, contextErr(err)
+	}
+	if err := db.wal.Append(command); err != nil {
+		return nil, errs.NewErrDbIO(err)
+	}
+	return apply()
+}
+
+// Report exceeded deadline of context as timeout
+func contextErr(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errs.NewErrTimeout()
+	}
+	return err
+}
+
+// Check if command changes db state
+func isMutating(command any) bool {
+	switch command.(type) {
+	case *CommandDropTable, *CommandCreateTable, *CommandInsert,
+		*CommandUpdate, *CommandDelete, *CommandRemoveDuplicates:
+		return true
+	default:
+		return false
+	}
+}
+
+func (db *Database) execute(command any) (*[]table.ColumnSet, error) {
+	return db.executeIn(context.Background(), db, command)
+}
+
+// Tables, that commands are executed on: db itself or transaction
+type tableScope interface {
+	// Table to read
+	table(name string) (*table.T, error)
+	// Table to change
+	tableForUpdate(name string) (*table.T, error)
+	addTable(name string, t *table.T)
+	removeTable(name string) error
+	// Table, that replaced closed one, after it was dropped or replaced
+	// by commit of transaction
+	reopenTable(name string, closed *table.T) (*table.T, error)
+}
+
+// Execute command on tables of scope, scans stop once ctx is done
+func (db *Database) executeIn(ctx context.Context, s tableScope, command any) (*[]table.ColumnSet, error) {
+	apply, err := db.prepare(ctx, s, command)
+	if err != nil {
+		return nil, contextErr(err)
+	}
+	res, err := apply()
+	return res, contextErr(err)
+}
+
+// Command, that is validated and ready to be applied
+type preparedCommand func() (*[]table.ColumnSet, error)
+
+// Validate command and find rows it changes without changing tables
+func (db *Database) prepare(ctx context.Context, s tableScope, command any) (preparedCommand, error) {
 	switch typedCommand := command.(type) {
 	case *CommandDropTable:
-		return db.dropTable(*typedCommand)
+		return db.prepareDropTable(s, *typedCommand)
 	case *CommandCreateTable:
-		return db.createTable(*typedCommand)
+		return db.prepareCreateTable(s, *typedCommand)
 	case *CommandInsert:
-		return db.runInsert(*typedCommand)
+		return db.prepareInsert(s, *typedCommand)
 	case *CommandSelect:
-		return db.runSelect(*typedCommand)
+		return db.prepareSelect(ctx, s, *typedCommand)
 	case *CommandUpdate:
-		return db.runUpdate(*typedCommand)
+		return db.prepareUpdate(ctx, s, *typedCommand)
 	case *CommandDelete:
-		return db.runDelete(*typedCommand)
+		return db.prepareDelete(ctx, s, *typedCommand)
 	case *CommandRemoveDuplicates:
-		return db.runRemoveDuplicates(*typedCommand)
+		return db.prepareRemoveDuplicates(ctx, s, *typedCommand)
 	default:
 		return nil, fmt.Errorf("unknown command type: %T", typedCommand)
 	}
@@ -175,31 +397,44 @@ type CommandDropTable struct {
 }
 
 // Drop table from db
-func (d *Database) dropTable(command CommandDropTable) (*[]table.ColumnSet, error) {
-	_, ok := d.tables[command.Name]
-	if !ok {
-		return nil, errs.NewErrTableNotFound(command.Name)
+func (d *Database) prepareDropTable(s tableScope, command CommandDropTable) (preparedCommand, error) {
+	if _, err := s.table(command.Name); err != nil {
+		return nil, err
 	}
-	delete(d.tables, command.Name)
-	return &[]table.ColumnSet{0: {"message": fmt.Sprintf("successfully dropped table %s", command.Name)}}, nil
+	return func() (*[]table.ColumnSet, error) {
+		if err := s.removeTable(command.Name); err != nil {
+			return nil, err
+		}
+		return &[]table.ColumnSet{0: {"message": fmt.Sprintf("successfully dropped table %s", command.Name)}}, nil
+	}, nil
 }
 
 type CommandCreateTable struct {
 	Name   string
 	Schema schema.T
+	// Storage engine of table, memory by default
+	Engine table.EngineKind
 }
 
 // Create new table in db
-func (d *Database) createTable(command CommandCreateTable) (*[]table.ColumnSet, error) {
-	if _, ok := d.tables[command.Name]; ok {
+func (d *Database) prepareCreateTable(s tableScope, command CommandCreateTable) (preparedCommand, error) {
+	if _, err := s.table(command.Name); err == nil {
 		return nil, errs.NewErrTableAlreadyExists(command.Name)
 	}
-	createdTable, err := table.NewTable(command.Schema)
-	if err != nil {
+	if err := table.ValidateSchema(command.Schema); err != nil {
+		return nil, err
+	}
+	if _, err := table.ParseEngineKind(string(command.Engine)); err != nil {
 		return nil, err
 	}
-	d.tables[command.Name] = createdTable
-	return &[]table.ColumnSet{0: {"message": fmt.Sprintf("successfully created table %s", command.Name)}}, nil
+	return func() (*[]table.ColumnSet, error) {
+		createdTable, err := d.newTable(command.Name, command.Schema, command.Engine)
+		if err != nil {
+			return nil, err
+		}
+		s.addTable(command.Name, createdTable)
+		return &[]table.ColumnSet{0: {"message": fmt.Sprintf("successfully created table %s", command.Name)}}, nil
+	}, nil
 }
 
 type CommandInsert struct {
@@ -208,83 +443,105 @@ type CommandInsert struct {
 }
 
 // Insert rows to db table
-func (d *Database) runInsert(command CommandInsert) (*[]table.ColumnSet, error) {
-	to, err := d.table(command.To)
+func (d *Database) prepareInsert(s tableScope, command CommandInsert) (preparedCommand, error) {
+	to, err := s.tableForUpdate(command.To)
 	if err != nil {
 		return nil, err
 	}
-	inserted, err := to.InsertRows(command.Data)
+	change, err := to.PrepareInsert(command.Data)
 	if err != nil {
 		return nil, err
 	}
-	return &[]table.ColumnSet{0: {
-			"message": fmt.Sprintf("successfully inserted %d %s to table %s",
-				inserted,
-				english.PluralWord(int(inserted), "row", ""),
-				command.To)}},
-		nil
+	return func() (*[]table.ColumnSet, error) {
+		inserted, err := change.Apply()
+		if err != nil {
+			return nil, err
+		}
+		return &[]table.ColumnSet{0: {
+				"message": fmt.Sprintf("successfully inserted %d %s to table %s",
+					inserted,
+					english.PluralWord(int(inserted), "row", ""),
+					command.To)}},
+			nil
+	}, nil
 }
 
 type CommandSelect struct {
-	From       string
-	Fields     *[]string
+	From   string
+	Fields *[]string
+	// Raw condition, see table.Condition
 	Conditions table.ColumnSet
 }
 
 // Select rows from db table
-func (d *Database) runSelect(command CommandSelect) (*[]table.ColumnSet, error) {
-	to, err := d.table(command.From)
+func (d *Database) prepareSelect(ctx context.Context, s tableScope, command CommandSelect) (preparedCommand, error) {
+	to, err := s.table(command.From)
 	if err != nil {
 		return nil, err
 	}
-	rows, err := to.SelectRows(command.Fields, command.Conditions)
-	if err != nil {
-		return nil, err
-	}
-	return rows, nil
+	return func() (*[]table.ColumnSet, error) {
+		for {
+			rows, err := to.SelectRows(ctx, command.Fields, command.Conditions)
+			if !errors.Is(err, table.ErrClosed) {
+				return rows, err
+			}
+			if to, err = s.reopenTable(command.From, to); err != nil {
+				return nil, err
+			}
+		}
+	}, nil
 }
 
 type CommandUpdate struct {
-	TableName  string
+	TableName string
+	// Raw condition, see table.Condition
 	Conditions table.ColumnSet
 	Data       table.ColumnSet
+	// Version, that updated rows must have, it is not checked if nil
+	ExpectedVersion *int64
 }
 
 // Update rows in db table
-func (d *Database) runUpdate(command CommandUpdate) (*[]table.ColumnSet, error) {
-	to, err := d.table(command.TableName)
+func (d *Database) prepareUpdate(ctx context.Context, s tableScope, command CommandUpdate) (preparedCommand, error) {
+	to, err := s.tableForUpdate(command.TableName)
 	if err != nil {
 		return nil, err
 	}
-	rowsCount, err := to.UpdateRows(command.Conditions, command.Data)
+	change, err := to.PrepareUpdate(ctx, command.Conditions, command.Data, command.ExpectedVersion)
 	if err != nil {
 		return nil, err
 	}
-	return &[]table.ColumnSet{0: {"message": fmt.Sprintf("successfully updated %d %s in table %s",
-		rowsCount,
-		english.PluralWord(int(rowsCount), "row", ""),
-		command.TableName)}}, nil
+	return func() (*[]table.ColumnSet, error) {
+		rowsCount, err := change.Apply()
+		if err != nil {
+			return nil, err
+		}
+		return &[]table.ColumnSet{0: {"message": fmt.Sprintf("successfully updated %d %s in table %s",
+			rowsCount,
+			english.PluralWord(int(rowsCount), "row", ""),
+			command.TableName)}}, nil
+	}, nil
 }
 
 type CommandDelete struct {
-	From       string
+	From string
+	// Raw condition, see table.Condition
 	Conditions table.ColumnSet
+	// Version, that deleted rows must have, it is not checked if nil
+	ExpectedVersion *int64
 }
 
 // Delete rows from db table
-func (d *Database) runDelete(command CommandDelete) (*[]table.ColumnSet, error) {
-	to, err := d.table(command.From)
+func (d *Database) prepareDelete(ctx context.Context, s tableScope, command CommandDelete) (preparedCommand, error) {
+	to, err := s.tableForUpdate(command.From)
 	if err != nil {
 		return nil, err
 	}
-	rowsCount, err := to.DeleteRows(command.Conditions)
+	change, err := to.PrepareDelete(ctx, command.Conditions, command.ExpectedVersion)
 	if err != nil {
 		return nil, err
 	}
-	return &[]table.ColumnSet{0: {"message": fmt.Sprintf("successfully deleted %d %s from table %s",
-		rowsCount,
-		english.PluralWord(int(rowsCount), "row", ""),
-		command.From)}}, nil
+	return deletedMessage(change, command.From), nil
 }
 
 type CommandRemoveDuplicates struct {
@@ -292,22 +549,75 @@ type CommandRemoveDuplicates struct {
 }
 
 // Delete duplicate rows from db table
-func (d *Database) runRemoveDuplicates(command CommandRemoveDuplicates) (*[]table.ColumnSet, error) {
-	to, err := d.table(command.From)
+func (d *Database) prepareRemoveDuplicates(ctx context.Context, s tableScope, command CommandRemoveDuplicates) (preparedCommand, error) {
+	to, err := s.tableForUpdate(command.From)
+	if err != nil {
+		return nil, err
+	}
+	change, err := to.PrepareDeleteDuplicates(ctx)
 	if err != nil {
 		return nil, err
 	}
-	rowsCount, err := to.DeleteDuplicates()
+	return deletedMessage(change, command.From), nil
+}
+
+// Apply change, that deletes rows from table, and report deleted rows count
+func deletedMessage(change *table.Change, tableName string) preparedCommand {
+	return func() (*[]table.ColumnSet, error) {
+		rowsCount, err := change.Apply()
+		if err != nil {
+			return nil, err
+		}
+		return &[]table.ColumnSet{0: {"message": fmt.Sprintf("successfully deleted %d %s from table %s",
+			rowsCount,
+			english.PluralWord(int(rowsCount), "row", ""),
+			tableName)}}, nil
+	}
+}
+
+// Tables of db are changed in place. Caller must hold writeMu to change them.
+func (d *Database) tableForUpdate(name string) (*table.T, error) {
+	return d.table(name)
+}
+
+func (d *Database) addTable(name string, t *table.T) {
+	d.tablesMu.Lock()
+	d.tables[name] = t
+	d.tablesMu.Unlock()
+}
+
+// Selects and dumps keep reading snapshots of removed table, storage is
+// released once they are done
+func (d *Database) removeTable(name string) error {
+	d.tablesMu.Lock()
+	removed, ok := d.tables[name]
+	delete(d.tables, name)
+	d.tablesMu.Unlock()
+	if !ok {
+		return errs.NewErrTableNotFound(name)
+	}
+	if err := removed.Close(); err != nil {
+		log.Printf("drop table %s: %s", name, err.Error())
+	}
+	return nil
+}
+
+// Table is closed by drop or commit only after it is removed from db,
+// so the same closed table means that db is closed
+func (d *Database) reopenTable(name string, closed *table.T) (*table.T, error) {
+	t, err := d.table(name)
 	if err != nil {
 		return nil, err
 	}
-	return &[]table.ColumnSet{0: {"message": fmt.Sprintf("successfully deleted %d %s from table %s",
-		rowsCount,
-		english.PluralWord(int(rowsCount), "row", ""),
-		command.From)}}, nil
+	if t == closed {
+		return nil, table.ErrClosed
+	}
+	return t, nil
 }
 
 func (d *Database) table(name string) (*table.T, error) {
+	d.tablesMu.RLock()
+	defer d.tablesMu.RUnlock()
 	table, ok := d.tables[name]
 	if !ok {
 		return nil, errs.NewErrTableNotFound(name)
@@ -1,31 +1,37 @@
 package db
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/ssyrota/frog-db/src/core/db/codec"
 	dbtypes "github.com/ssyrota/frog-db/src/core/db/dbtypes"
 	"github.com/ssyrota/frog-db/src/core/db/schema"
+	"github.com/ssyrota/frog-db/src/core/db/snapshot"
 	"github.com/ssyrota/frog-db/src/core/db/table"
 	errs "github.com/ssyrota/frog-db/src/core/err"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestExecute(t *testing.T) {
-	dumpPath := ".test.json"
-	os.Setenv("DUMP_PATH", dumpPath)
-	defer os.Remove(dumpPath)
-
 	validTableSchema := schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}
 	tableName := "frog"
-	validCreateCommand := &CommandCreateTable{tableName, validTableSchema}
+	validCreateCommand := &CommandCreateTable{Name: tableName, Schema: validTableSchema}
 	invalidSchema := schema.T{"invalid_type_column": "unknown_type"}
 
 	t.Run("fails on unknown command type", func(t *testing.T) {
-		db, err := New(dumpPath, time.Second)
+		db, err := New(testContext(t), tempDumpPath(t), time.Second)
 		assert.Nil(t, err)
 		assert.NoError(t, err, "")
 		_, err = db.Execute("unknown smooth command")
@@ -37,7 +43,7 @@ func TestExecute(t *testing.T) {
 	t.Run("CreateTable with IntrospectSchema", func(t *testing.T) {
 		t.Run("accepts schema with valid data types and with introspect returns provided schema",
 			func(t *testing.T) {
-				db, err := New(dumpPath, time.Second)
+				db, err := New(testContext(t), tempDumpPath(t), time.Second)
 				assert.Nil(t, err)
 				assert.NotNil(t, db)
 				createRes, err := db.Execute(validCreateCommand)
@@ -50,7 +56,7 @@ func TestExecute(t *testing.T) {
 		)
 		t.Run("fails on create table with duplicate name",
 			func(t *testing.T) {
-				db, err := New(dumpPath, time.Second)
+				db, err := New(testContext(t), tempDumpPath(t), time.Second)
 				assert.Nil(t, err)
 				assert.NotNil(t, db)
 				_, err = db.Execute(validCreateCommand)
@@ -62,10 +68,10 @@ func TestExecute(t *testing.T) {
 		)
 		t.Run("fails on invalid dataType in schema provided",
 			func(t *testing.T) {
-				db, err := New(dumpPath, time.Second)
+				db, err := New(testContext(t), tempDumpPath(t), time.Second)
 				assert.Nil(t, err)
 				assert.NotNil(t, db)
-				_, err = db.Execute(&CommandCreateTable{"frog", invalidSchema})
+				_, err = db.Execute(&CommandCreateTable{Name: "frog", Schema: invalidSchema})
 				assert.NotNil(t, err)
 				assert.EqualError(t, err, fmt.Sprintf("cannot create column %s with type %s", "invalid_type_column", "unknown_type"))
 			},
@@ -74,7 +80,7 @@ func TestExecute(t *testing.T) {
 
 	t.Run("DropTable", func(t *testing.T) {
 		t.Run("drops existed table", func(t *testing.T) {
-			db, err := New(dumpPath, time.Second)
+			db, err := New(testContext(t), tempDumpPath(t), time.Second)
 			assert.Nil(t, err)
 			assert.NotNil(t, db)
 			_, err = db.Execute(validCreateCommand)
@@ -90,7 +96,7 @@ func TestExecute(t *testing.T) {
 			assert.NotNil(t, err)
 		})
 		t.Run("fails on drop non existed table", func(t *testing.T) {
-			db, err := New(dumpPath, time.Second)
+			db, err := New(testContext(t), tempDumpPath(t), time.Second)
 			assert.Nil(t, err)
 			assert.NotNil(t, db)
 			dropResult, err := db.Execute(&CommandDropTable{"frog"})
@@ -102,8 +108,8 @@ func TestExecute(t *testing.T) {
 
 	t.Run("Insert", func(t *testing.T) {
 		t.Run("accepts and save input with required columns and valid types", func(t *testing.T) {
-			db, _ := New(dumpPath, time.Second)
-			db.Execute(&CommandCreateTable{"frog", schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
+			db, _ := New(testContext(t), tempDumpPath(t), time.Second)
+			db.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
 			rows := &[]table.ColumnSet{
 				{"leg_length": float64(1), "jump": []float64{2.2, 3.3}},
 				{"leg_length": float64(2), "jump": []float64{2.5, 3.5}}}
@@ -116,16 +122,16 @@ func TestExecute(t *testing.T) {
 			assert.Equal(t, *rows, (*selectResult))
 		})
 		t.Run("fail input without required columns", func(t *testing.T) {
-			db, _ := New(dumpPath, time.Second)
-			db.Execute(&CommandCreateTable{"frog", schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
+			db, _ := New(testContext(t), tempDumpPath(t), time.Second)
+			db.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
 			rows := &[]table.ColumnSet{{"leg_length": 1}}
 			_, err := db.Execute(&CommandInsert{"frog", rows})
 			assert.NotNil(t, err)
 			assert.IsType(t, &errs.ErrColumnsRequired{}, err)
 		})
 		t.Run("fail input with unexpected columns", func(t *testing.T) {
-			db, _ := New(dumpPath, time.Second)
-			db.Execute(&CommandCreateTable{"frog", schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
+			db, _ := New(testContext(t), tempDumpPath(t), time.Second)
+			db.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
 			rows := &[]table.ColumnSet{
 				{"unknown": 1, "leg_length": 2, "jump": []float64{2.5, 3.5}}}
 			_, err := db.Execute(&CommandInsert{"frog", rows})
@@ -133,8 +139,8 @@ func TestExecute(t *testing.T) {
 			assert.IsType(t, &errs.ErrColumnsNotFound{}, err)
 		})
 		t.Run("fail input with columns type mismatch", func(t *testing.T) {
-			db, _ := New(dumpPath, time.Second)
-			db.Execute(&CommandCreateTable{"frog", schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
+			db, _ := New(testContext(t), tempDumpPath(t), time.Second)
+			db.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
 			rows := &[]table.ColumnSet{
 				{"leg_length": "short", "jump": []float64{2.5, 3.5}}}
 			_, err := db.Execute(&CommandInsert{"frog", rows})
@@ -145,8 +151,8 @@ func TestExecute(t *testing.T) {
 
 	t.Run("Select", func(t *testing.T) {
 		t.Run("accepts valid conditions and fields and return data, that matches conditions", func(t *testing.T) {
-			db, _ := New(dumpPath, time.Second)
-			db.Execute(&CommandCreateTable{"frog", schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
+			db, _ := New(testContext(t), tempDumpPath(t), time.Second)
+			db.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
 			rows := &[]table.ColumnSet{
 				{"leg_length": float64(1), "jump": []float64{2.2, 3.3}},
 				{"leg_length": float64(2), "jump": []float64{2.5, 3.5}}}
@@ -157,8 +163,8 @@ func TestExecute(t *testing.T) {
 			assert.Equal(t, selectResult, &[]table.ColumnSet{{"jump": []float64{2.2, 3.3}}})
 		})
 		t.Run("is idempotent", func(t *testing.T) {
-			db, _ := New(dumpPath, time.Second)
-			db.Execute(&CommandCreateTable{"frog", schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
+			db, _ := New(testContext(t), tempDumpPath(t), time.Second)
+			db.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
 			rows := &[]table.ColumnSet{
 				{"leg_length": float64(1), "jump": []float64{2.2, 3.3}},
 				{"leg_length": float64(2), "jump": []float64{2.5, 3.5}}}
@@ -172,21 +178,21 @@ func TestExecute(t *testing.T) {
 
 	t.Run("Update", func(t *testing.T) {
 		t.Run("fail on invalid update data", func(t *testing.T) {
-			db, _ := New(dumpPath, time.Second)
-			db.Execute(&CommandCreateTable{"frog", schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
+			db, _ := New(testContext(t), tempDumpPath(t), time.Second)
+			db.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
 			rows := &[]table.ColumnSet{
 				{"leg_length": float64(1), "jump": []float64{2.2, 3.3}},
 				{"leg_length": float64(2), "jump": []float64{2.5, 3.5}}}
 			db.Execute(&CommandInsert{"frog", rows})
 
 			updateFields := table.ColumnSet{"jump": []float64{10, 9}}
-			_, err := db.Execute(&CommandUpdate{"frog", table.ColumnSet{"leg_length": 1}, updateFields})
+			_, err := db.Execute(&CommandUpdate{TableName: "frog", Conditions: table.ColumnSet{"leg_length": 1}, Data: updateFields})
 			assert.NotNil(t, err)
 		})
 		t.Run("accepts valid conditions and updates table rows", func(t *testing.T) {
-			db, _ := New(dumpPath, time.Second)
+			db, _ := New(testContext(t), tempDumpPath(t), time.Second)
 			tableName := "frog"
-			db.Execute(&CommandCreateTable{tableName, schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
+			db.Execute(&CommandCreateTable{Name: tableName, Schema: schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
 			rows := &[]table.ColumnSet{
 				{"leg_length": float64(1), "jump": []float64{2.2, 3.3}},
 				{"leg_length": float64(2), "jump": []float64{2.5, 3.5}}}
@@ -194,7 +200,7 @@ func TestExecute(t *testing.T) {
 
 			updateConditions := table.ColumnSet{"leg_length": 1}
 			updateFields := table.ColumnSet{"jump": []float64{10, 11}}
-			updateResult, err := db.Execute(&CommandUpdate{tableName, updateConditions, updateFields})
+			updateResult, err := db.Execute(&CommandUpdate{TableName: tableName, Conditions: updateConditions, Data: updateFields})
 			assert.Nil(t, err)
 			assert.NotNil(t, updateResult)
 			assert.Equal(t, &[]table.ColumnSet{{"message": "successfully updated 1 row in table frog"}}, updateResult)
@@ -205,14 +211,14 @@ func TestExecute(t *testing.T) {
 
 	t.Run("Delete", func(t *testing.T) {
 		t.Run("delete data by valid conditions", func(t *testing.T) {
-			db, _ := New(dumpPath, time.Second)
-			db.Execute(&CommandCreateTable{"frog", schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
+			db, _ := New(testContext(t), tempDumpPath(t), time.Second)
+			db.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
 			rows := &[]table.ColumnSet{
 				{"leg_length": float64(1), "jump": []float64{2.2, 3.3}},
 				{"leg_length": float64(2), "jump": []float64{2.5, 3.5}}}
 			db.Execute(&CommandInsert{"frog", rows})
 
-			deleteRes, err := db.Execute(&CommandDelete{"frog", table.ColumnSet{"leg_length": float64(1)}})
+			deleteRes, err := db.Execute(&CommandDelete{From: "frog", Conditions: table.ColumnSet{"leg_length": float64(1)}})
 			assert.NoError(t, err)
 			assert.Equal(t, &[]table.ColumnSet{{"message": "successfully deleted 1 row from table frog"}}, deleteRes)
 
@@ -223,8 +229,8 @@ func TestExecute(t *testing.T) {
 	})
 
 	t.Run("RemoveDuplicates", func(t *testing.T) {
-		db, _ := New(dumpPath, time.Second)
-		db.Execute(&CommandCreateTable{"frog", schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
+		db, _ := New(testContext(t), tempDumpPath(t), time.Second)
+		db.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
 		rows := &[]table.ColumnSet{
 			{"leg_length": float64(1), "jump": []float64{2.2, 3.3}},
 			{"leg_length": float64(1), "jump": []float64{2.2, 3.3}},
@@ -244,10 +250,8 @@ func TestExecute(t *testing.T) {
 // Test save dump to file and create db from dump.
 func TestDump(t *testing.T) {
 	t.Run("save and upload", func(t *testing.T) {
-		dumpPath := ".test_dump.json"
-		os.Setenv("DUMP_PATH", dumpPath)
-		database, err := New(dumpPath, time.Second)
-		defer os.Remove(dumpPath)
+		dumpPath := tempDumpPath(t)
+		database, err := New(testContext(t), dumpPath, time.Second)
 
 		assert.NoError(t, err)
 		tables := []string{"frog", "leg"}
@@ -266,9 +270,7 @@ func TestDump(t *testing.T) {
 		err = database.StoreDump()
 		assert.NoError(t, err)
 
-		os.Setenv("DUMP_PATH", ".new_dump.json")
-		defer os.Remove(".new_dump.json")
-		newDb, err := New(".new_dump.json", time.Second)
+		newDb, err := New(testContext(t), tempDumpPath(t), time.Second)
 		assert.NoError(t, err)
 		err = newDb.FromDump(dumpPath)
 		assert.NoError(t, err)
@@ -276,4 +278,1723 @@ func TestDump(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, 10, len(*selectRes))
 	})
+	t.Run("shorter dump replaces longer one", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"leg_length": dbtypes.Real}})
+		database.Execute(&CommandCreateTable{Name: "leg", Schema: schema.T{"leg_length": dbtypes.Real}})
+		assert.NoError(t, database.StoreDump())
+		database.Execute(&CommandDropTable{Name: "leg"})
+		assert.NoError(t, database.StoreDump())
+
+		tmpFiles, err := filepath.Glob(filepath.Join(filepath.Dir(dumpPath), tmpPattern(dumpPath)))
+		assert.NoError(t, err)
+		assert.Empty(t, tmpFiles)
+		restarted, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		dbSchema, err := restarted.IntrospectSchema()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]schema.T{"frog": {"leg_length": dbtypes.Real}}, dbSchema)
+	})
+}
+
+// Test binary snapshot format of dump.
+func TestSnapshot(t *testing.T) {
+	allTypesSchema := schema.T{
+		"id": dbtypes.Integer, "leg_length": dbtypes.Real, "sex": dbtypes.Char,
+		"name": dbtypes.String, "jump": dbtypes.RealInv, "photo": dbtypes.Image}
+	rows := &[]table.ColumnSet{
+		{"id": 1, "leg_length": 1.5, "sex": "m", "name": "kermit", "jump": []float64{2.2, 3.3}, "photo": "https://frog.png"},
+		{"id": -7, "leg_length": 0.25, "sex": "f", "name": "", "jump": []float64{-1, 0}, "photo": ""}}
+	expected := []table.ColumnSet{
+		{"id": int64(1), "leg_length": 1.5, "sex": 'm', "name": "kermit", "jump": []float64{2.2, 3.3}, "photo": "https://frog.png"},
+		{"id": int64(-7), "leg_length": 0.25, "sex": 'f', "name": "", "jump": []float64{-1, 0}, "photo": ""}}
+
+	t.Run("keeps typed values on restart", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database, err := New(testContext(t), dumpPath, time.Hour, WithDumpFormat(DumpBinary))
+		assert.NoError(t, err)
+		_, err = database.Execute(&CommandCreateTable{Name: "frog", Schema: allTypesSchema})
+		assert.NoError(t, err)
+		_, err = database.Execute(&CommandInsert{"frog", rows})
+		assert.NoError(t, err)
+		assert.NoError(t, database.StoreDump())
+		header, err := os.ReadFile(segmentPath(t, dumpPath, "frog"))
+		assert.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(header, []byte("FROGSNAP")))
+
+		restarted, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		selectRes, err := restarted.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, *selectRes)
+	})
+	t.Run("is detected by FromDump", func(t *testing.T) {
+		binaryPath := tempDumpPath(t)
+		source, err := New(testContext(t), binaryPath, time.Hour, WithDumpFormat(DumpBinary))
+		assert.NoError(t, err)
+		source.Execute(&CommandCreateTable{Name: "frog", Schema: allTypesSchema})
+		source.Execute(&CommandInsert{"frog", rows})
+		assert.NoError(t, source.StoreDump())
+
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		assert.NoError(t, database.FromDump(binaryPath))
+		selectRes, err := database.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, *selectRes)
+	})
+	t.Run("rejects truncated snapshot", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database, err := New(testContext(t), dumpPath, time.Hour, WithDumpFormat(DumpBinary))
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: allTypesSchema})
+		database.Execute(&CommandInsert{"frog", rows})
+		assert.NoError(t, database.StoreDump())
+		raw, err := os.ReadFile(segmentPath(t, dumpPath, "frog"))
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(segmentPath(t, dumpPath, "frog"), raw[:len(raw)-1], 0644))
+
+		_, err = New(testContext(t), dumpPath, time.Hour)
+		var checksumErr *errs.ErrDumpChecksum
+		assert.ErrorAs(t, err, &checksumErr)
+	})
+	t.Run("rejects corrupted columns count", func(t *testing.T) {
+		raw := binary.LittleEndian.AppendUint16([]byte(snapshot.Magic), snapshot.Version)
+		raw = append(raw, 'T', 4, 'f', 'r', 'o', 'g', 0)
+		raw = binary.AppendUvarint(raw, 1<<40)
+		reader, err := snapshot.NewReader(bytes.NewReader(raw))
+		assert.NoError(t, err)
+		_, err = reader.NextRows(func(*table.Dump, table.ColumnSet) error { return nil })
+		assert.ErrorContains(t, err, "limit")
+	})
+}
+
+// Test compression of dumps.
+func TestDumpCodec(t *testing.T) {
+	rows := &[]table.ColumnSet{{"name": "kermit", "photo": "https://frog.png"}}
+	for _, format := range []DumpFormat{DumpJson, DumpBinary} {
+		t.Run(fmt.Sprintf("gzip %s dump is detected on load", format), func(t *testing.T) {
+			dumpPath := tempDumpPath(t)
+			database, err := New(testContext(t), dumpPath, time.Hour, WithDumpFormat(format), WithDumpCodec(codec.Gzip))
+			assert.NoError(t, err)
+			database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"name": dbtypes.String, "photo": dbtypes.Image}})
+			database.Execute(&CommandInsert{"frog", rows})
+			assert.NoError(t, database.StoreDump())
+			raw, err := os.ReadFile(segmentPath(t, dumpPath, "frog"))
+			assert.NoError(t, err)
+			assert.True(t, bytes.HasPrefix(raw, codec.Gzip.Magic()))
+
+			restarted, err := New(testContext(t), dumpPath, time.Hour)
+			assert.NoError(t, err)
+			selectRes, err := restarted.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+			assert.NoError(t, err)
+			assert.Equal(t, *rows, *selectRes)
+		})
+	}
+	t.Run("json dump stream is compressed", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour, WithDumpCodec(codec.Gzip))
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"name": dbtypes.String, "photo": dbtypes.Image}})
+		database.Execute(&CommandInsert{"frog", rows})
+		var compressed bytes.Buffer
+		for msg := range database.JsonDump() {
+			assert.NoError(t, msg.Err)
+			compressed.Write(msg.Payload)
+		}
+		reader, err := codec.Gzip.NewReader(&compressed)
+		assert.NoError(t, err)
+		var dump Dump
+		assert.NoError(t, json.NewDecoder(reader).Decode(&dump))
+		assert.Equal(t, "frog", dump[0].Name)
+		assert.Len(t, dump[0].Data, 1)
+	})
+}
+
+// Test incremental dumps.
+func TestIncrementalDump(t *testing.T) {
+	modTimes := func(t *testing.T, dumpPath string) map[string]time.Time {
+		res := map[string]time.Time{}
+		for _, name := range []string{dumpPath, segmentPath(t, dumpPath, "frog"), segmentPath(t, dumpPath, "leg")} {
+			info, err := os.Stat(name)
+			assert.NoError(t, err)
+			res[name] = info.ModTime()
+		}
+		return res
+	}
+	t.Run("idle db does not rewrite dump", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"leg_length": dbtypes.Real}})
+		database.Execute(&CommandCreateTable{Name: "leg", Schema: schema.T{"leg_length": dbtypes.Real}})
+		assert.NoError(t, database.StoreDump())
+		before := modTimes(t, dumpPath)
+		time.Sleep(10 * time.Millisecond)
+		assert.NoError(t, database.StoreDump())
+		assert.Equal(t, before, modTimes(t, dumpPath))
+	})
+	t.Run("rewrites segment of changed table only", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"leg_length": dbtypes.Real}})
+		database.Execute(&CommandCreateTable{Name: "leg", Schema: schema.T{"leg_length": dbtypes.Real}})
+		assert.NoError(t, database.StoreDump())
+		legSegment := segmentPath(t, dumpPath, "leg")
+		frogSegment := segmentPath(t, dumpPath, "frog")
+
+		_, err = database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"leg_length": 1}}})
+		assert.NoError(t, err)
+		assert.NoError(t, database.StoreDump())
+		assert.Equal(t, legSegment, segmentPath(t, dumpPath, "leg"))
+		assert.NotEqual(t, frogSegment, segmentPath(t, dumpPath, "frog"))
+		_, err = os.Stat(frogSegment)
+		assert.True(t, os.IsNotExist(err))
+
+		restarted, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		selectRes, err := restarted.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Equal(t, []table.ColumnSet{{"leg_length": float64(1)}}, *selectRes)
+	})
+}
+
+// Test point-in-time snapshot of db for dumps.
+func TestDumpSnapshot(t *testing.T) {
+	t.Run("json dump is not affected by writes after call", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"leg_length": dbtypes.Real}})
+		database.Execute(&CommandCreateTable{Name: "leg", Schema: schema.T{"leg_length": dbtypes.Real}})
+		database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"leg_length": 1}}})
+		database.Execute(&CommandInsert{"leg", &[]table.ColumnSet{{"leg_length": 1}}})
+
+		dumpCh := database.JsonDump()
+		_, err = database.Execute(&CommandUpdate{TableName: "frog", Conditions: table.ColumnSet{}, Data: table.ColumnSet{"leg_length": 2}})
+		assert.NoError(t, err)
+		_, err = database.Execute(&CommandInsert{"leg", &[]table.ColumnSet{{"leg_length": 3}}})
+		assert.NoError(t, err)
+		var raw bytes.Buffer
+		for msg := range dumpCh {
+			assert.NoError(t, msg.Err)
+			raw.Write(msg.Payload)
+		}
+		var dump Dump
+		assert.NoError(t, json.Unmarshal(raw.Bytes(), &dump))
+		for _, dumpTable := range dump {
+			assert.Equal(t, []table.ColumnSet{{"leg_length": float64(1), table.VersionColumn: float64(1)}}, dumpTable.Data)
+		}
+	})
+	t.Run("keeps wal records written during dump", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"leg_length": dbtypes.Real}})
+		database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"leg_length": 1}}})
+
+		database.dumpMu.Lock()
+		database.writeMu.Lock()
+		snap, err := database.snapshot()
+		database.writeMu.Unlock()
+		assert.NoError(t, err)
+		_, err = database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"leg_length": 2}}})
+		assert.NoError(t, err)
+		assert.NoError(t, database.storeSnapshot(context.Background(), snap))
+		database.dumpMu.Unlock()
+
+		restarted, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		selectRes, err := restarted.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Equal(t, []table.ColumnSet{{"leg_length": float64(1)}, {"leg_length": float64(2)}}, *selectRes)
+	})
+}
+
+// Test rotation of stored dumps.
+func TestDumpRetention(t *testing.T) {
+	insertAndDump := func(t *testing.T, database *Database, legLength float64) {
+		_, err := database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"leg_length": legLength}}})
+		assert.NoError(t, err)
+		assert.NoError(t, database.StoreDump())
+	}
+	t.Run("keeps only the latest dump by default", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"leg_length": dbtypes.Real}})
+		for i := 0; i < 3; i++ {
+			insertAndDump(t, database, float64(i))
+		}
+		dumps, err := database.ListDumps()
+		assert.NoError(t, err)
+		assert.Len(t, dumps, 1)
+		entries, err := os.ReadDir(segmentsDir(database.path))
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+	})
+	t.Run("keeps last N dumps and restores any of them", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour, WithDumpRetention(Retention{Count: 3}))
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"leg_length": dbtypes.Real}})
+		for i := 0; i < 5; i++ {
+			insertAndDump(t, database, float64(i))
+		}
+		dumps, err := database.ListDumps()
+		assert.NoError(t, err)
+		assert.Len(t, dumps, 3)
+		assert.True(t, dumps[0].Time.After(dumps[1].Time))
+		assert.Equal(t, 1, dumps[2].Tables)
+		assert.NotZero(t, dumps[2].Size)
+
+		assert.NoError(t, database.FromDump(dumps[2].ID))
+		selectRes, err := database.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Len(t, *selectRes, 3)
+	})
+	t.Run("keeps dumps younger than max age", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour, WithDumpRetention(Retention{MaxAge: time.Hour}))
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"leg_length": dbtypes.Real}})
+		for i := 0; i < 3; i++ {
+			insertAndDump(t, database, float64(i))
+		}
+		dumps, err := database.ListDumps()
+		assert.NoError(t, err)
+		assert.Len(t, dumps, 3)
+		assert.NoError(t, database.pruneDumps(time.Now().Add(2*time.Hour)))
+		dumps, err = database.ListDumps()
+		assert.NoError(t, err)
+		assert.Len(t, dumps, 1)
+	})
+}
+
+// Test integrity checks of dumps.
+func TestDumpChecksum(t *testing.T) {
+	rows := &[]table.ColumnSet{{"name": "kermit"}, {"name": "pepe"}}
+	newDumpedDb := func(t *testing.T, opts ...Option) (*Database, string) {
+		dumpPath := tempDumpPath(t)
+		database, err := New(testContext(t), dumpPath, time.Hour, opts...)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"name": dbtypes.String}})
+		database.Execute(&CommandInsert{"frog", rows})
+		assert.NoError(t, database.StoreDump())
+		return database, dumpPath
+	}
+	flipByte := func(t *testing.T, path string, find string) {
+		raw, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		i := bytes.Index(raw, []byte(find))
+		assert.NotEqual(t, -1, i)
+		raw[i] ^= 1
+		assert.NoError(t, os.WriteFile(path, raw, 0644))
+	}
+	assertRejected := func(t *testing.T, database *Database, dumpPath string) {
+		target, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		target.Execute(&CommandCreateTable{Name: "leg", Schema: schema.T{"length": dbtypes.Real}})
+		err = target.FromDump(dumpPath)
+		var checksumErr *errs.ErrDumpChecksum
+		assert.ErrorAs(t, err, &checksumErr)
+		dbSchema, err := target.IntrospectSchema()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]schema.T{"leg": {"length": dbtypes.Real}}, dbSchema)
+	}
+
+	t.Run("rejects bit-flipped segment", func(t *testing.T) {
+		database, dumpPath := newDumpedDb(t)
+		flipByte(t, segmentPath(t, dumpPath, "frog"), "pepe")
+		assertRejected(t, database, dumpPath)
+	})
+	t.Run("rejects tampered manifest", func(t *testing.T) {
+		database, dumpPath := newDumpedDb(t)
+		flipByte(t, dumpPath, `frog","file"`)
+		assertRejected(t, database, dumpPath)
+	})
+	t.Run("rejects bit-flipped section of binary snapshot", func(t *testing.T) {
+		database, dumpPath := newDumpedDb(t, WithDumpFormat(DumpBinary))
+		snapshotPath := segmentPath(t, dumpPath, "frog")
+		flipByte(t, snapshotPath, "pepe")
+		assertRejected(t, database, snapshotPath)
+	})
+	t.Run("rejects segment without checksum", func(t *testing.T) {
+		database, dumpPath := newDumpedDb(t)
+		m, err := readManifest(dumpPath)
+		assert.NoError(t, err)
+		m.Segments[0].Checksum = ""
+		assert.NoError(t, writeManifest(dumpPath, m))
+		assertRejected(t, database, dumpPath)
+	})
+	for _, file := range []string{"../dump.json", "dump.json.d/../../dump.json", `dump.json.d\..\..\dump.json`, "/etc/passwd", "", "."} {
+		t.Run(fmt.Sprintf("rejects segment file %q", file), func(t *testing.T) {
+			_, dumpPath := newDumpedDb(t)
+			m, err := readManifest(dumpPath)
+			assert.NoError(t, err)
+			m.Segments[0].File = file
+			assert.NoError(t, writeManifest(dumpPath, m))
+
+			target, err := New(testContext(t), tempDumpPath(t), time.Hour)
+			assert.NoError(t, err)
+			err = target.FromDump(dumpPath)
+			var corruptedErr *errs.ErrCorruptedDump
+			assert.ErrorAs(t, err, &corruptedErr)
+			dbSchema, err := target.IntrospectSchema()
+			assert.NoError(t, err)
+			assert.Empty(t, dbSchema)
+		})
+	}
+}
+
+// Test recovery of stored data on db creation.
+func TestRecover(t *testing.T) {
+	t.Run("loads existing dump on start", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		_, err = database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"name": dbtypes.String, "sex": dbtypes.Char}})
+		assert.NoError(t, err)
+		rows := &[]table.ColumnSet{{"name": "kermit", "sex": "m"}}
+		_, err = database.Execute(&CommandInsert{"frog", rows})
+		assert.NoError(t, err)
+		assert.NoError(t, database.StoreDump())
+
+		restarted, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		selectRes, err := restarted.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Equal(t, []table.ColumnSet{{"name": "kermit", "sex": 'm'}}, *selectRes)
+	})
+	t.Run("refuses to start on corrupted dump", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		corrupted := []byte(`[{"schema":{"leg_length":"real"},"data":[{"leg_length":"long"}],"name":"frog"}]`)
+		assert.NoError(t, os.WriteFile(dumpPath, corrupted, 0644))
+
+		_, err := New(testContext(t), dumpPath, time.Hour)
+		assert.Error(t, err)
+		var corruptedErr *errs.ErrCorruptedDump
+		assert.ErrorAs(t, err, &corruptedErr)
+		stored, err := os.ReadFile(dumpPath)
+		assert.NoError(t, err)
+		assert.Equal(t, corrupted, stored)
+	})
+}
+
+// Test replay of write-ahead log on restart.
+func TestWal(t *testing.T) {
+	t.Run("replays acknowledged commands after restart", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		_, err = database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"leg_length": dbtypes.Real, "jump": dbtypes.RealInv}})
+		assert.NoError(t, err)
+		rows := &[]table.ColumnSet{
+			{"leg_length": float64(1), "jump": []float64{2.2, 3.3}},
+			{"leg_length": float64(2), "jump": []float64{2.5, 3.5}}}
+		_, err = database.Execute(&CommandInsert{"frog", rows})
+		assert.NoError(t, err)
+		_, err = database.Execute(&CommandDelete{From: "frog", Conditions: table.ColumnSet{"leg_length": 2}})
+		assert.NoError(t, err)
+		// Rejected commands are not logged
+		logged, err := os.Stat(walPath(dumpPath))
+		assert.NoError(t, err)
+		_, err = database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"leg_length": 1}}})
+		assert.Error(t, err)
+		_, err = database.Execute(&CommandDelete{From: "toad"})
+		assert.Error(t, err)
+		_, err = database.Execute(&CommandCreateTable{Name: "toad", Schema: schema.T{"leg_length": "unknown"}})
+		assert.Error(t, err)
+		info, err := os.Stat(walPath(dumpPath))
+		assert.NoError(t, err)
+		assert.Equal(t, logged.Size(), info.Size())
+
+		restarted, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		selectRes, err := restarted.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Equal(t, []table.ColumnSet{{"leg_length": float64(1), "jump": []float64{2.2, 3.3}}}, *selectRes)
+	})
+	t.Run("is truncated after dump", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		_, err = database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"leg_length": dbtypes.Real}})
+		assert.NoError(t, err)
+		info, err := os.Stat(walPath(dumpPath))
+		assert.NoError(t, err)
+		assert.NotZero(t, info.Size())
+
+		assert.NoError(t, database.StoreDump())
+		info, err = os.Stat(walPath(dumpPath))
+		assert.NoError(t, err)
+		assert.Zero(t, info.Size())
+	})
+	// Db with table and five inserts logged
+	newLogged := func(t *testing.T) string {
+		dumpPath := tempDumpPath(t)
+		database, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		_, err = database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"id": dbtypes.Integer}})
+		assert.NoError(t, err)
+		for i := 0; i < 5; i++ {
+			_, err = database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"id": i}}})
+			assert.NoError(t, err)
+		}
+		return dumpPath
+	}
+	t.Run("refuses to start with corrupted record", func(t *testing.T) {
+		dumpPath := newLogged(t)
+		raw, err := os.ReadFile(walPath(dumpPath))
+		assert.NoError(t, err)
+		// The last byte of the first record
+		raw[8+binary.LittleEndian.Uint32(raw)-1] ^= 1
+		assert.NoError(t, os.WriteFile(walPath(dumpPath), raw, 0644))
+
+		_, err = New(testContext(t), dumpPath, time.Hour)
+		var corrupted *errs.ErrCorruptedWal
+		assert.ErrorAs(t, err, &corrupted)
+		kept, err := os.ReadFile(walPath(dumpPath))
+		assert.NoError(t, err)
+		assert.Equal(t, raw, kept)
+	})
+	t.Run("cuts torn record off", func(t *testing.T) {
+		dumpPath := newLogged(t)
+		raw, err := os.ReadFile(walPath(dumpPath))
+		assert.NoError(t, err)
+		// Header of record, that is longer than the rest of log
+		torn := binary.LittleEndian.AppendUint32(nil, math.MaxUint32)
+		torn = append(torn, 0, 0, 0, 0, 1, 2, 3)
+		assert.NoError(t, os.WriteFile(walPath(dumpPath), append(raw, torn...), 0644))
+
+		restarted, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		res, err := restarted.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Len(t, *res, 5)
+		info, err := os.Stat(walPath(dumpPath))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(raw)), info.Size())
+	})
+}
+
+// Test multi-statement transactions.
+func TestTx(t *testing.T) {
+	frogSchema := schema.T{"id": dbtypes.Integer}
+	newDb := func(t *testing.T, dumpPath string) *Database {
+		database, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		return database
+	}
+	selectAll := func(t *testing.T, database interface {
+		Execute(command any) (*[]table.ColumnSet, error)
+	}, tableName string) []table.ColumnSet {
+		res, err := database.Execute(&CommandSelect{tableName, &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		return *res
+	}
+	for _, engine := range []table.EngineKind{table.MemoryEngine, table.DiskEngine} {
+		t.Run(fmt.Sprintf("commit is atomic with %s engine", engine), func(t *testing.T) {
+			database := newDb(t, tempDumpPath(t))
+			database.Execute(&CommandCreateTable{Name: "frog", Schema: frogSchema, Engine: engine})
+			database.Execute(&CommandCreateTable{Name: "toad", Schema: frogSchema, Engine: engine})
+			database.Execute(&CommandInsert{"toad", &[]table.ColumnSet{{"id": 1}, {"id": 2}}})
+
+			tx := database.Begin()
+			_, err := tx.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"id": 1}}})
+			assert.NoError(t, err)
+			_, err = tx.Execute(&CommandDelete{From: "toad", Conditions: table.ColumnSet{"id": 1}})
+			assert.NoError(t, err)
+			assert.Equal(t, []table.ColumnSet{{"id": int64(1)}}, selectAll(t, tx, "frog"))
+			assert.Equal(t, []table.ColumnSet{{"id": int64(2)}}, selectAll(t, tx, "toad"))
+			// Changes are not visible outside before commit
+			assert.Empty(t, selectAll(t, database, "frog"))
+			assert.Len(t, selectAll(t, database, "toad"), 2)
+
+			assert.NoError(t, tx.Commit())
+			assert.Equal(t, []table.ColumnSet{{"id": int64(1)}}, selectAll(t, database, "frog"))
+			assert.Equal(t, []table.ColumnSet{{"id": int64(2)}}, selectAll(t, database, "toad"))
+			assert.IsType(t, &errs.ErrTxDone{}, tx.Commit())
+		})
+	}
+	t.Run("rollback discards changes", func(t *testing.T) {
+		database := newDb(t, tempDumpPath(t))
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: frogSchema})
+		tx := database.Begin()
+		tx.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"id": 1}}})
+		_, err := tx.Execute(&CommandCreateTable{Name: "toad", Schema: frogSchema, Engine: table.DiskEngine})
+		assert.NoError(t, err)
+		_, err = tx.Execute(&CommandDropTable{Name: "frog"})
+		assert.NoError(t, err)
+		_, err = tx.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.IsType(t, &errs.ErrTableNotFound{}, err)
+		assert.NoError(t, tx.Rollback())
+
+		dbSchema, err := database.IntrospectSchema()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]schema.T{"frog": frogSchema}, dbSchema)
+		assert.Empty(t, selectAll(t, database, "frog"))
+		_, err = tx.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"id": 1}}})
+		assert.IsType(t, &errs.ErrTxDone{}, err)
+	})
+	t.Run("conflicting commit fails", func(t *testing.T) {
+		database := newDb(t, tempDumpPath(t))
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: frogSchema})
+		tx := database.Begin()
+		tx.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"id": 1}}})
+		database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"id": 2}}})
+		assert.IsType(t, &errs.ErrTxConflict{}, tx.Commit())
+		assert.Equal(t, []table.ColumnSet{{"id": int64(2)}}, selectAll(t, database, "frog"))
+
+		tx = database.Begin()
+		tx.Execute(&CommandCreateTable{Name: "toad", Schema: frogSchema})
+		database.Execute(&CommandCreateTable{Name: "toad", Schema: frogSchema})
+		assert.IsType(t, &errs.ErrTxConflict{}, tx.Commit())
+	})
+	t.Run("committed transaction is replayed from wal", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database := newDb(t, dumpPath)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: frogSchema})
+		tx := database.Begin()
+		tx.Execute(&CommandCreateTable{Name: "toad", Schema: frogSchema})
+		tx.Execute(&CommandInsert{"toad", &[]table.ColumnSet{{"id": 1}}})
+		tx.Execute(&CommandDropTable{Name: "frog"})
+		assert.NoError(t, tx.Commit())
+		rolledBack := database.Begin()
+		rolledBack.Execute(&CommandInsert{"toad", &[]table.ColumnSet{{"id": 2}}})
+		assert.NoError(t, rolledBack.Rollback())
+
+		restarted := newDb(t, dumpPath)
+		dbSchema, err := restarted.IntrospectSchema()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]schema.T{"toad": frogSchema}, dbSchema)
+		assert.Equal(t, []table.ColumnSet{{"id": int64(1)}}, selectAll(t, restarted, "toad"))
+	})
+	t.Run("command changed by caller after execution is logged as executed", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database := newDb(t, dumpPath)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: frogSchema})
+		tx := database.Begin()
+		rows := []table.ColumnSet{{"id": 1}}
+		_, err := tx.Execute(&CommandInsert{"frog", &rows})
+		assert.NoError(t, err)
+		rows[0]["id"] = 2
+		assert.NoError(t, tx.Commit())
+		assert.Equal(t, []table.ColumnSet{{"id": int64(1)}}, selectAll(t, database, "frog"))
+
+		restarted := newDb(t, dumpPath)
+		assert.Equal(t, []table.ColumnSet{{"id": int64(1)}}, selectAll(t, restarted, "frog"))
+	})
+}
+
+// Test atomic batches of commands.
+func TestExecuteBatch(t *testing.T) {
+	frogSchema := schema.T{"id": dbtypes.Integer}
+	t.Run("returns results in order", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		results, err := database.ExecuteBatch([]any{
+			&CommandCreateTable{Name: "frog", Schema: frogSchema},
+			&CommandInsert{"frog", &[]table.ColumnSet{{"id": 1}, {"id": 2}}},
+			&CommandUpdate{TableName: "frog", Conditions: table.ColumnSet{"id": 2}, Data: table.ColumnSet{"id": 3}},
+			&CommandDelete{From: "frog", Conditions: table.ColumnSet{"id": 1}},
+			&CommandSelect{"frog", &[]string{}, table.ColumnSet{}},
+		})
+		assert.NoError(t, err)
+		assert.Len(t, results, 5)
+		assert.Equal(t, "successfully created table frog", (*results[0])[0]["message"])
+		assert.Equal(t, "successfully inserted 2 rows to table frog", (*results[1])[0]["message"])
+		assert.Equal(t, []table.ColumnSet{{"id": int64(3)}}, *results[4])
+	})
+	t.Run("failed command discards batch", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		_, err = database.ExecuteBatch([]any{
+			&CommandCreateTable{Name: "frog", Schema: frogSchema},
+			&CommandInsert{"frog", &[]table.ColumnSet{{"id": 1}}},
+			&CommandInsert{"toad", &[]table.ColumnSet{{"id": 1}}},
+		})
+		var notFound *errs.ErrTableNotFound
+		assert.ErrorAs(t, err, &notFound)
+		assert.Contains(t, err.Error(), "command 2")
+		dbSchema, err := database.IntrospectSchema()
+		assert.NoError(t, err)
+		assert.Empty(t, dbSchema)
+	})
+}
+
+// Test cancellation and deadlines of commands and dumps.
+func TestExecuteContext(t *testing.T) {
+	frogSchema := schema.T{"id": dbtypes.Integer}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	newFrogs := func(t *testing.T, dumpPath string) *Database {
+		database, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: frogSchema})
+		database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"id": 1}, {"id": 2}}})
+		return database
+	}
+	t.Run("done context stops commands", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database := newFrogs(t, dumpPath)
+		_, err := database.ExecuteContext(cancelled, &CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = database.ExecuteContext(expired, &CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.IsType(t, &errs.ErrTimeout{}, err)
+		_, err = database.ExecuteContext(expired, &CommandDelete{From: "frog", Conditions: table.ColumnSet{}})
+		assert.IsType(t, &errs.ErrTimeout{}, err)
+		_, err = database.ExecuteBatchContext(expired, []any{&CommandDelete{From: "frog", Conditions: table.ColumnSet{}}})
+		var timeout *errs.ErrTimeout
+		assert.ErrorAs(t, err, &timeout)
+		assert.NoError(t, database.Close())
+
+		// Cancelled commands are not logged
+		restarted, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		res, err := restarted.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Len(t, *res, 2)
+	})
+	t.Run("done context stops scans", func(t *testing.T) {
+		database := newFrogs(t, tempDumpPath(t))
+		frogs, err := database.table("frog")
+		assert.NoError(t, err)
+		_, err = frogs.SelectRows(cancelled, &[]string{}, table.ColumnSet{})
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = frogs.UpdateRows(expired, table.ColumnSet{}, table.ColumnSet{"id": 3}, nil)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		_, err = frogs.DeleteRows(cancelled, table.ColumnSet{}, nil)
+		assert.ErrorIs(t, err, context.Canceled)
+		res, err := database.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Equal(t, []table.ColumnSet{{"id": int64(1)}, {"id": int64(2)}}, *res)
+	})
+	t.Run("context done during scan cancels command", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database := newFrogs(t, dumpPath)
+		for _, command := range []any{
+			&CommandUpdate{TableName: "frog", Conditions: table.ColumnSet{}, Data: table.ColumnSet{"id": 3}},
+			&CommandDelete{From: "frog", Conditions: table.ColumnSet{}},
+			&CommandRemoveDuplicates{From: "frog"},
+		} {
+			// Context is done after the first row is scanned
+			_, err := database.ExecuteContext(&cancelAfter{Context: context.Background(), checks: 3}, command)
+			assert.ErrorIs(t, err, context.Canceled)
+		}
+		res, err := database.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Equal(t, []table.ColumnSet{{"id": int64(1)}, {"id": int64(2)}}, *res)
+		assert.NoError(t, database.Close())
+
+		restarted, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		res, err = restarted.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Equal(t, []table.ColumnSet{{"id": int64(1)}, {"id": int64(2)}}, *res)
+	})
+	t.Run("select is not blocked by scan of writer", func(t *testing.T) {
+		database := newFrogs(t, tempDumpPath(t))
+		// Writer is paused on the first row of its scan
+		paused := &pauseAfter{Context: context.Background(), checks: 2, paused: make(chan struct{}), resume: make(chan struct{})}
+		written := make(chan error)
+		go func() {
+			_, err := database.ExecuteContext(paused, &CommandDelete{From: "frog", Conditions: table.ColumnSet{"id": 1}})
+			written <- err
+		}()
+		<-paused.paused
+		selected := make(chan *[]table.ColumnSet)
+		go func() {
+			res, err := database.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+			assert.NoError(t, err)
+			selected <- res
+		}()
+		select {
+		case res := <-selected:
+			assert.Equal(t, []table.ColumnSet{{"id": int64(1)}, {"id": int64(2)}}, *res)
+		case <-time.After(5 * time.Second):
+			t.Fatal("select is blocked by writer")
+		}
+		close(paused.resume)
+		assert.NoError(t, <-written)
+		res, err := database.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Equal(t, []table.ColumnSet{{"id": int64(2)}}, *res)
+	})
+	t.Run("done context stops dumps", func(t *testing.T) {
+		database := newFrogs(t, tempDumpPath(t))
+		assert.ErrorIs(t, database.StoreDumpContext(cancelled), context.Canceled)
+		assert.IsType(t, &errs.ErrTimeout{}, database.StoreDumpContext(expired))
+		dumps, err := database.ListDumps()
+		assert.NoError(t, err)
+		assert.Empty(t, dumps)
+		status, err := database.DumpStatus()
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), status.Failures)
+
+		var dumpErr error
+		for msg := range database.JsonDumpContext(cancelled) {
+			if msg.Err != nil {
+				dumpErr = msg.Err
+			}
+		}
+		assert.ErrorIs(t, dumpErr, context.Canceled)
+	})
+}
+
+// Context, that is cancelled once its error is checked more than checks times
+type cancelAfter struct {
+	context.Context
+	checks int
+}
+
+func (c *cancelAfter) Err() error {
+	if c.checks == 0 {
+		return context.Canceled
+	}
+	c.checks--
+	return nil
+}
+
+// Context, that pauses once its error is checked more than checks times,
+// until resume is closed
+type pauseAfter struct {
+	context.Context
+	checks int
+	paused chan struct{}
+	resume chan struct{}
+}
+
+func (c *pauseAfter) Err() error {
+	if c.checks == 0 {
+		close(c.paused)
+		<-c.resume
+	}
+	c.checks--
+	return nil
+}
+
+// Test comparison operators of conditions.
+func TestConditions(t *testing.T) {
+	frogSchema := schema.T{"id": dbtypes.Integer, "leg_length": dbtypes.Real, "name": dbtypes.String, "jump": dbtypes.RealInv}
+	newFrogs := func(t *testing.T, dumpPath string) *Database {
+		database, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: frogSchema})
+		_, err = database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{
+			{"id": 1, "leg_length": 2.5, "name": "a", "jump": []any{1, 2}},
+			{"id": 2, "leg_length": 3.5, "name": "b", "jump": []any{1, 3}},
+			{"id": 3, "leg_length": 4.5, "name": "c", "jump": []any{2, 3}},
+		}})
+		assert.NoError(t, err)
+		return database
+	}
+	ids := func(t *testing.T, database *Database, conditions table.ColumnSet) []int64 {
+		res, err := database.Execute(&CommandSelect{"frog", &[]string{"id"}, conditions})
+		assert.NoError(t, err)
+		if err != nil {
+			return nil
+		}
+		ids := []int64{}
+		for _, row := range *res {
+			ids = append(ids, row["id"].(int64))
+		}
+		return ids
+	}
+	for _, tc := range []struct {
+		name       string
+		conditions table.ColumnSet
+		ids        []int64
+	}{
+		{"plain equality", table.ColumnSet{"name": "b"}, []int64{2}},
+		{"eq", table.ColumnSet{"name": map[string]any{"eq": "b"}}, []int64{2}},
+		{"ne", table.ColumnSet{"name": map[string]any{"ne": "b"}}, []int64{1, 3}},
+		{"gt", table.ColumnSet{"leg_length": map[string]any{"gt": 3.5}}, []int64{3}},
+		{"gte", table.ColumnSet{"leg_length": map[string]any{"gte": 3.5}}, []int64{2, 3}},
+		{"lt", table.ColumnSet{"id": map[string]any{"lt": "2"}}, []int64{1}},
+		{"lte", table.ColumnSet{"name": map[string]any{"lte": "b"}}, []int64{1, 2}},
+		{"in", table.ColumnSet{"id": map[string]any{"in": []any{1, 3, 5}}}, []int64{1, 3}},
+		{"notIn", table.ColumnSet{"jump": map[string]any{"notIn": []any{[]any{1, 2}}}}, []int64{2, 3}},
+		{"between", table.ColumnSet{"leg_length": map[string]any{"between": []any{3, 5}}}, []int64{2, 3}},
+		{"operators of column", table.ColumnSet{"id": map[string]any{"gt": 1, "lte": 2}}, []int64{2}},
+		{"operators of columns", table.ColumnSet{"id": map[string]any{"ne": 2}, "leg_length": map[string]any{"lt": 3}}, []int64{1}},
+		{"any of", table.ColumnSet{table.AnyOf: []any{
+			map[string]any{"name": "a"},
+			map[string]any{"leg_length": map[string]any{"gt": 2}, "id": map[string]any{"lt": 3}}}}, []int64{1, 2}},
+		{"all of", table.ColumnSet{table.AllOf: []table.ColumnSet{{"id": map[string]any{"gt": 1}}, {"name": map[string]any{"ne": "c"}}}}, []int64{2}},
+		{"not", table.ColumnSet{table.Not: map[string]any{"name": "b"}}, []int64{1, 3}},
+		{"nested", table.ColumnSet{"id": map[string]any{"ne": 1}, table.AnyOf: []any{
+			map[string]any{table.Not: map[string]any{"leg_length": map[string]any{"gt": 3}}},
+			map[string]any{table.AllOf: []any{map[string]any{"name": "c"}}}}}, []int64{3}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			database := newFrogs(t, tempDumpPath(t))
+			assert.Equal(t, tc.ids, ids(t, database, tc.conditions))
+		})
+	}
+	t.Run("invalid conditions", func(t *testing.T) {
+		database := newFrogs(t, tempDumpPath(t))
+		for _, conditions := range []table.ColumnSet{
+			{"id": map[string]any{"like": 1}},
+			{"id": map[string]any{}},
+			{"jump": map[string]any{"gt": []any{1, 2}}},
+			{"id": map[string]any{"in": 1}},
+			{"id": map[string]any{"between": []any{1}}},
+			{"id": map[string]any{"between": []any{2, 1}}},
+			{table.AnyOf: []any{}},
+			{table.AllOf: map[string]any{"id": 1}},
+			{table.AnyOf: []any{map[string]any{"id": 1}, "id"}},
+			{table.Not: []any{map[string]any{"id": 1}}},
+			{table.AnyOf: []any{map[string]any{table.Not: map[string]any{"jump": map[string]any{"lt": 1}}}}},
+		} {
+			_, err := database.Execute(&CommandSelect{"frog", &[]string{}, conditions})
+			assert.IsType(t, &errs.ErrInvalidCondition{}, err, conditions)
+		}
+		_, err := database.Execute(&CommandDelete{From: "frog", Conditions: table.ColumnSet{"id": map[string]any{"gt": "one"}}})
+		assert.Error(t, err)
+		_, err = database.Execute(&CommandUpdate{TableName: "frog", Conditions: table.ColumnSet{"weight": map[string]any{"gt": 1}}, Data: table.ColumnSet{"id": 1}})
+		assert.IsType(t, &errs.ErrColumnsNotFound{}, err)
+		_, err = database.Execute(&CommandDelete{From: "frog", Conditions: table.ColumnSet{table.Not: map[string]any{"weight": 1}}})
+		assert.IsType(t, &errs.ErrColumnsNotFound{}, err)
+		_, err = database.Execute(&CommandCreateTable{Name: "toad", Schema: schema.T{table.AnyOf: dbtypes.Integer}})
+		assert.IsType(t, &errs.ErrReservedColumn{}, err)
+		assert.Equal(t, []int64{1, 2, 3}, ids(t, database, table.ColumnSet{}))
+	})
+	t.Run("update and delete are replayed", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database := newFrogs(t, dumpPath)
+		_, err := database.Execute(&CommandUpdate{TableName: "frog", Conditions: table.ColumnSet{"leg_length": map[string]any{"gt": 3}}, Data: table.ColumnSet{"name": "long"}})
+		assert.NoError(t, err)
+		_, err = database.Execute(&CommandDelete{From: "frog", Conditions: table.ColumnSet{table.AnyOf: []any{
+			map[string]any{"id": 1},
+			map[string]any{table.AllOf: []any{map[string]any{"name": "long"}, map[string]any{"leg_length": map[string]any{"lt": 4}}}}}}})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{3}, ids(t, database, table.ColumnSet{"name": "long"}))
+
+		restarted, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{3}, ids(t, restarted, table.ColumnSet{"name": "long"}))
+		assert.Equal(t, []int64{3}, ids(t, restarted, table.ColumnSet{}))
+	})
+	t.Run("update and delete with nested column sets are replayed", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database := newFrogs(t, dumpPath)
+		_, err := database.Execute(&CommandUpdate{TableName: "frog", Conditions: table.ColumnSet{table.Not: table.ColumnSet{"id": 1}}, Data: table.ColumnSet{"name": "long"}})
+		assert.NoError(t, err)
+		_, err = database.Execute(&CommandDelete{From: "frog", Conditions: table.ColumnSet{table.AnyOf: []table.ColumnSet{
+			{"id": table.ColumnSet{"eq": 1}},
+			{table.AllOf: []table.ColumnSet{{"name": "long"}, {"leg_length": table.ColumnSet{"lt": 4}}}}}}})
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{3}, ids(t, database, table.ColumnSet{}))
+
+		restarted, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{3}, ids(t, restarted, table.ColumnSet{"name": "long"}))
+		assert.Equal(t, []int64{3}, ids(t, restarted, table.ColumnSet{}))
+	})
+}
+
+// Test optimistic concurrency with row versions.
+func TestRowVersions(t *testing.T) {
+	frogSchema := schema.T{"id": dbtypes.Integer, "name": dbtypes.String}
+	versions := func(t *testing.T, database *Database) []table.ColumnSet {
+		res, err := database.Execute(&CommandSelect{"frog", &[]string{"id", table.VersionColumn}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		return *res
+	}
+	version := func(v int64) *int64 { return &v }
+	t.Run("update and delete check expected version", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: frogSchema})
+		database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"id": 1, "name": "a"}, {"id": 2, "name": "b"}}})
+		res, err := database.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{"id": 1}})
+		assert.NoError(t, err)
+		assert.Equal(t, []table.ColumnSet{{"id": int64(1), "name": "a"}}, *res)
+
+		_, err = database.Execute(&CommandUpdate{TableName: "frog", Conditions: table.ColumnSet{"id": 1}, Data: table.ColumnSet{"name": "c"}, ExpectedVersion: version(1)})
+		assert.NoError(t, err)
+		_, err = database.Execute(&CommandUpdate{TableName: "frog", Conditions: table.ColumnSet{"id": 1}, Data: table.ColumnSet{"name": "d"}, ExpectedVersion: version(1)})
+		assert.IsType(t, &errs.ErrVersionConflict{}, err)
+		_, err = database.Execute(&CommandDelete{From: "frog", Conditions: table.ColumnSet{}, ExpectedVersion: version(1)})
+		assert.IsType(t, &errs.ErrVersionConflict{}, err)
+		assert.Equal(t, []table.ColumnSet{
+			{"id": int64(1), table.VersionColumn: int64(2)},
+			{"id": int64(2), table.VersionColumn: int64(1)}}, versions(t, database))
+
+		_, err = database.Execute(&CommandDelete{From: "frog", Conditions: table.ColumnSet{"id": 1}, ExpectedVersion: version(2)})
+		assert.NoError(t, err)
+		assert.Equal(t, []table.ColumnSet{{"id": int64(2), table.VersionColumn: int64(1)}}, versions(t, database))
+	})
+	t.Run("version column is reserved", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		_, err = database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{table.VersionColumn: dbtypes.Integer}})
+		assert.IsType(t, &errs.ErrReservedColumn{}, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: frogSchema})
+		_, err = database.Execute(&CommandUpdate{TableName: "frog", Conditions: table.ColumnSet{}, Data: table.ColumnSet{table.VersionColumn: 5}})
+		assert.IsType(t, &errs.ErrColumnsNotFound{}, err)
+	})
+	for _, tc := range []struct {
+		format DumpFormat
+		engine table.EngineKind
+	}{{DumpJson, table.MemoryEngine}, {DumpBinary, table.MemoryEngine}, {DumpJson, table.DiskEngine}} {
+		t.Run(fmt.Sprintf("versions survive restart with %s dump and %s engine", tc.format, tc.engine), func(t *testing.T) {
+			dumpPath := tempDumpPath(t)
+			database, err := New(testContext(t), dumpPath, time.Hour, WithDumpFormat(tc.format))
+			assert.NoError(t, err)
+			database.Execute(&CommandCreateTable{Name: "frog", Schema: frogSchema, Engine: tc.engine})
+			database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"id": 1, "name": "a"}, {"id": 2, "name": "b"}}})
+			database.Execute(&CommandUpdate{TableName: "frog", Conditions: table.ColumnSet{"id": 1}, Data: table.ColumnSet{"name": "c"}})
+			assert.NoError(t, database.StoreDump())
+			// Covered by wal only
+			database.Execute(&CommandUpdate{TableName: "frog", Conditions: table.ColumnSet{"id": 2}, Data: table.ColumnSet{"name": "d"}})
+
+			restarted, err := New(testContext(t), dumpPath, time.Hour)
+			assert.NoError(t, err)
+			assert.Equal(t, []table.ColumnSet{
+				{"id": int64(1), table.VersionColumn: int64(2)},
+				{"id": int64(2), table.VersionColumn: int64(2)}}, versions(t, restarted))
+		})
+	}
+}
+
+// Test that selects read consistent snapshots of tables.
+func TestMvcc(t *testing.T) {
+	for _, engine := range []table.EngineKind{table.MemoryEngine, table.DiskEngine} {
+		t.Run(fmt.Sprintf("select sees whole changes with %s engine", engine), func(t *testing.T) {
+			database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+			assert.NoError(t, err)
+			database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"id": dbtypes.Integer, "jump": dbtypes.Integer}, Engine: engine})
+			const batchSize = 10
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 1; i <= 50; i++ {
+					// Every row has the same jump after each command
+					batch := make([]table.ColumnSet, batchSize)
+					for j := range batch {
+						batch[j] = table.ColumnSet{"id": j, "jump": i - 1}
+					}
+					database.Execute(&CommandInsert{"frog", &batch})
+					database.Execute(&CommandUpdate{TableName: "frog", Conditions: table.ColumnSet{}, Data: table.ColumnSet{"jump": i}})
+				}
+			}()
+			for running := true; running; {
+				select {
+				case <-done:
+					running = false
+				default:
+				}
+				res, err := database.Execute(&CommandSelect{"frog", &[]string{"jump"}, table.ColumnSet{}})
+				assert.NoError(t, err)
+				assert.Zero(t, len(*res)%batchSize)
+				for _, row := range *res {
+					assert.Equal(t, (*res)[0], row)
+				}
+			}
+		})
+	}
+	t.Run("gc reclaims replaced rows of disk table", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database, err := New(testContext(t), dumpPath, time.Hour, WithGcInterval(time.Hour))
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"id": dbtypes.Integer, "name": dbtypes.String}, Engine: table.DiskEngine})
+		rows := make([]table.ColumnSet, 100)
+		for i := range rows {
+			rows[i] = table.ColumnSet{"id": i, "name": strings.Repeat("frog", 10)}
+		}
+		database.Execute(&CommandInsert{"frog", &rows})
+		database.Execute(&CommandUpdate{TableName: "frog", Conditions: table.ColumnSet{}, Data: table.ColumnSet{"name": "toad"}})
+		files, err := filepath.Glob(filepath.Join(enginesDir(dumpPath), "*.pages"))
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+		before, err := os.Stat(files[0])
+		assert.NoError(t, err)
+
+		assert.NoError(t, database.collectGarbage())
+		after, err := os.Stat(files[0])
+		assert.NoError(t, err)
+		assert.Less(t, after.Size()*4, before.Size())
+		res, err := database.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{"id": 1}})
+		assert.NoError(t, err)
+		assert.Equal(t, []table.ColumnSet{{"id": int64(1), "name": "toad"}}, *res)
+	})
+}
+
+// Test tables stored by disk engine.
+func TestDiskEngine(t *testing.T) {
+	frogSchema := schema.T{"name": dbtypes.String, "leg_length": dbtypes.Real, "jump": dbtypes.RealInv}
+	createFrog := &CommandCreateTable{Name: "frog", Schema: frogSchema, Engine: table.DiskEngine}
+	t.Run("fails on unknown engine", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		_, err = database.Execute(&CommandCreateTable{Name: "frog", Schema: frogSchema, Engine: "tape"})
+		assert.Error(t, err)
+	})
+	t.Run("executes commands like memory engine", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		_, err = database.Execute(createFrog)
+		assert.NoError(t, err)
+		_, err = database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{
+			{"name": "a", "leg_length": 1, "jump": []float64{1, 2}},
+			{"name": "b", "leg_length": 2, "jump": []float64{1, 2}},
+			{"name": "a", "leg_length": 1, "jump": []float64{1, 2}},
+		}})
+		assert.NoError(t, err)
+		_, err = database.Execute(&CommandRemoveDuplicates{"frog"})
+		assert.NoError(t, err)
+		_, err = database.Execute(&CommandUpdate{TableName: "frog", Conditions: table.ColumnSet{"name": "b"}, Data: table.ColumnSet{"leg_length": 3}})
+		assert.NoError(t, err)
+		res, err := database.Execute(&CommandSelect{"frog", &[]string{"name", "leg_length"}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Equal(t, []table.ColumnSet{{"name": "a", "leg_length": float64(1)}, {"name": "b", "leg_length": float64(3)}}, *res)
+		_, err = database.Execute(&CommandDelete{From: "frog", Conditions: table.ColumnSet{"name": "a"}})
+		assert.NoError(t, err)
+		res, err = database.Execute(&CommandSelect{"frog", &[]string{"name"}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Equal(t, []table.ColumnSet{{"name": "b"}}, *res)
+	})
+	t.Run("engine survives restart", func(t *testing.T) {
+		for _, format := range []DumpFormat{DumpJson, DumpBinary} {
+			dumpPath := tempDumpPath(t)
+			database, err := New(testContext(t), dumpPath, time.Hour, WithDumpFormat(format))
+			assert.NoError(t, err)
+			database.Execute(createFrog)
+			database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"name": "a", "leg_length": 1, "jump": []float64{1, 2}}}})
+			assert.NoError(t, database.StoreDump())
+			database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"name": "b", "leg_length": 2, "jump": []float64{1, 2}}}})
+
+			restarted, err := New(testContext(t), dumpPath, time.Hour)
+			assert.NoError(t, err)
+			assert.Equal(t, table.DiskEngine, restarted.tables["frog"].EngineKind())
+			res, err := restarted.Execute(&CommandSelect{"frog", &[]string{"name"}, table.ColumnSet{}})
+			assert.NoError(t, err)
+			assert.Equal(t, []table.ColumnSet{{"name": "a"}, {"name": "b"}}, *res)
+		}
+	})
+	t.Run("dump is not affected by compaction", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		database.Execute(createFrog)
+		database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"name": "a", "leg_length": 0, "jump": []float64{1, 2}}}})
+		dumpCh := database.JsonDump()
+		// Every update appends a record, so file is compacted several times
+		photo := string(bytes.Repeat([]byte{'x'}, 4096))
+		for i := 1; i <= 1000; i++ {
+			_, err := database.Execute(&CommandUpdate{TableName: "frog", Conditions: table.ColumnSet{}, Data: table.ColumnSet{"name": photo, "leg_length": i}})
+			assert.NoError(t, err)
+		}
+		var raw bytes.Buffer
+		for msg := range dumpCh {
+			assert.NoError(t, msg.Err)
+			raw.Write(msg.Payload)
+		}
+		var dump Dump
+		assert.NoError(t, json.Unmarshal(raw.Bytes(), &dump))
+		assert.Equal(t, []table.ColumnSet{{"name": "a", "leg_length": float64(0), "jump": []any{float64(1), float64(2)}, table.VersionColumn: float64(1)}}, dump[0].Data)
+
+		entries, err := os.ReadDir(enginesDir(database.path))
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		info, err := entries[0].Info()
+		assert.NoError(t, err)
+		assert.Less(t, info.Size(), int64(4096*1000/2))
+		res, err := database.Execute(&CommandSelect{"frog", &[]string{"leg_length"}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Equal(t, []table.ColumnSet{{"leg_length": float64(1000)}}, *res)
+	})
+	t.Run("drop table removes its file", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		database.Execute(createFrog)
+		_, err = database.Execute(&CommandDropTable{"frog"})
+		assert.NoError(t, err)
+		entries, err := os.ReadDir(enginesDir(database.path))
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+	t.Run("transaction shares file with table", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		database.Execute(createFrog)
+		photo := string(bytes.Repeat([]byte{'x'}, 4096))
+		rows := make([]table.ColumnSet, 100)
+		for i := range rows {
+			rows[i] = table.ColumnSet{"name": photo, "leg_length": i, "jump": []float64{1, 2}}
+		}
+		_, err = database.Execute(&CommandInsert{"frog", &rows})
+		assert.NoError(t, err)
+		size := engineFilesSize(t, dumpPath)
+
+		tx := database.Begin()
+		_, err = tx.Execute(&CommandUpdate{TableName: "frog", Conditions: table.ColumnSet{"leg_length": 0}, Data: table.ColumnSet{"name": "b"}})
+		assert.NoError(t, err)
+		assert.Less(t, engineFilesSize(t, dumpPath), size+4096)
+		// Table and its copy append to the same file independently
+		_, err = database.Execute(&CommandUpdate{TableName: "frog", Conditions: table.ColumnSet{"leg_length": 1}, Data: table.ColumnSet{"name": "c"}})
+		assert.NoError(t, err)
+		selectFirst := &CommandSelect{"frog", &[]string{"name"}, table.ColumnSet{"leg_length": table.ColumnSet{"lte": 1}}}
+		res, err := tx.Execute(selectFirst)
+		assert.NoError(t, err)
+		assert.Equal(t, []table.ColumnSet{{"name": "b"}, {"name": photo}}, *res)
+		res, err = database.Execute(selectFirst)
+		assert.NoError(t, err)
+		assert.Equal(t, []table.ColumnSet{{"name": photo}, {"name": "c"}}, *res)
+		assert.NoError(t, tx.Rollback())
+	})
+	t.Run("closes replaced files once snapshots are released", func(t *testing.T) {
+		if _, err := os.Stat("/proc/self/fd"); err != nil {
+			t.Skip("open files are not listed")
+		}
+		dumpPath := tempDumpPath(t)
+		database, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		database.Execute(createFrog)
+		database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"name": "a", "leg_length": 0, "jump": []float64{1, 2}}}})
+		photo := string(bytes.Repeat([]byte{'x'}, 4096))
+		for i := 1; i <= 1000; i++ {
+			_, err := database.Execute(&CommandUpdate{TableName: "frog", Conditions: table.ColumnSet{}, Data: table.ColumnSet{"name": photo, "leg_length": i}})
+			assert.NoError(t, err)
+			if i%100 == 0 {
+				_, err = database.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+				assert.NoError(t, err)
+				assert.NoError(t, database.ExportCsv("frog", io.Discard))
+			}
+		}
+		assert.NoError(t, database.StoreDump())
+		tx := database.Begin()
+		_, err = tx.Execute(&CommandDelete{From: "frog"})
+		assert.NoError(t, err)
+		assert.NoError(t, tx.Commit())
+		assert.Equal(t, 1, openEngineFiles(t, dumpPath))
+
+		_, err = database.Execute(&CommandDropTable{"frog"})
+		assert.NoError(t, err)
+		assert.Zero(t, openEngineFiles(t, dumpPath))
+	})
+}
+
+// Total size of disk engine files of db
+func engineFilesSize(t *testing.T, dumpPath string) int64 {
+	entries, err := os.ReadDir(enginesDir(dumpPath))
+	assert.NoError(t, err)
+	var size int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		assert.NoError(t, err)
+		size += info.Size()
+	}
+	return size
+}
+
+// Count of open files of disk engines of db, removed files included
+func openEngineFiles(t *testing.T, dumpPath string) int {
+	fds, err := os.ReadDir("/proc/self/fd")
+	assert.NoError(t, err)
+	count := 0
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join("/proc/self/fd", fd.Name()))
+		if err == nil && strings.HasPrefix(target, enginesDir(dumpPath)) {
+			count++
+		}
+	}
+	return count
+}
+
+// Test csv import and export.
+func TestCsv(t *testing.T) {
+	allTypesSchema := schema.T{
+		"id": dbtypes.Integer, "leg_length": dbtypes.Real, "sex": dbtypes.Char,
+		"name": dbtypes.String, "jump": dbtypes.RealInv, "photo": dbtypes.Image}
+	rows := &[]table.ColumnSet{
+		{"id": 1, "leg_length": 1.5, "sex": "m", "name": "kermit, the frog", "jump": []float64{2.2, 3.3}, "photo": "https://frog.png"},
+		{"id": -7, "leg_length": 0.25, "sex": "f", "name": "\"quoted\"\nline", "jump": []float64{-1, 0}, "photo": ""}}
+	expectedCsv := "id,jump,leg_length,name,photo,sex\n" +
+		"1,\"[2.2,3.3]\",1.5,\"kermit, the frog\",https://frog.png,m\n" +
+		"-7,\"[-1,0]\",0.25,\"\"\"quoted\"\"\nline\",,f\n"
+
+	t.Run("exports typed values", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: allTypesSchema})
+		database.Execute(&CommandInsert{"frog", rows})
+		var out bytes.Buffer
+		assert.NoError(t, database.ExportCsv("frog", &out))
+		assert.Equal(t, expectedCsv, out.String())
+	})
+	t.Run("imports exported csv", func(t *testing.T) {
+		source, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		source.Execute(&CommandCreateTable{Name: "frog", Schema: allTypesSchema})
+		source.Execute(&CommandInsert{"frog", rows})
+		var out bytes.Buffer
+		assert.NoError(t, source.ExportCsv("frog", &out))
+
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: allTypesSchema})
+		res, err := database.ImportCsv("frog", &out)
+		assert.NoError(t, err)
+		assert.Equal(t, &ImportResult{Inserted: 2, Errors: []LineError{}}, res)
+		expected, err := source.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		imported, err := database.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, imported)
+	})
+	t.Run("skips invalid lines", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"id": dbtypes.Integer, "jump": dbtypes.RealInv}})
+		res, err := database.ImportCsv("frog", strings.NewReader("jump,id\n\"[1,2]\",1\n\"[1,2]\",x\n\"[3,1]\",3\n\"[1,2]\"\n\"[0,0]\",5\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, uint(2), res.Inserted)
+		assert.Equal(t, uint(3), res.Skipped)
+		lines := []int{}
+		for _, lineErr := range res.Errors {
+			lines = append(lines, lineErr.Line)
+		}
+		assert.Equal(t, []int{3, 4, 5}, lines)
+		selectRes, err := database.Execute(&CommandSelect{"frog", &[]string{"id"}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Equal(t, []table.ColumnSet{{"id": int64(1)}, {"id": int64(5)}}, *selectRes)
+	})
+	t.Run("fails on header not matching schema", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"id": dbtypes.Integer, "name": dbtypes.String}})
+		_, err = database.ImportCsv("frog", strings.NewReader("id\n1\n"))
+		assert.IsType(t, &errs.ErrColumnsRequired{}, err)
+		_, err = database.ImportCsv("frog", strings.NewReader("id,name,age\n1,a,2\n"))
+		assert.IsType(t, &errs.ErrColumnsNotFound{}, err)
+		_, err = database.ImportCsv("leg", strings.NewReader("id\n1\n"))
+		assert.IsType(t, &errs.ErrTableNotFound{}, err)
+	})
+}
+
+// Test newline delimited json import and export.
+func TestNdjson(t *testing.T) {
+	frogSchema := schema.T{"name": dbtypes.String, "sex": dbtypes.Char, "jump": dbtypes.RealInv}
+	t.Run("exports row per line", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: frogSchema})
+		database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{
+			{"name": "kermit", "sex": "m", "jump": []float64{1, 2}},
+			{"name": "piggy", "sex": "f", "jump": []float64{0, 0.5}}}})
+		var out bytes.Buffer
+		assert.NoError(t, database.ExportNdjson("frog", &out))
+		assert.Equal(t, `{"jump":[1,2],"name":"kermit","sex":109}`+"\n"+`{"jump":[0,0.5],"name":"piggy","sex":102}`+"\n", out.String())
+	})
+	t.Run("imports exported rows in batches", func(t *testing.T) {
+		source, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		source.Execute(&CommandCreateTable{Name: "frog", Schema: frogSchema})
+		for i := 0; i < 5; i++ {
+			source.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"name": fmt.Sprint(i), "sex": "m", "jump": []float64{0, float64(i)}}}})
+		}
+		var out bytes.Buffer
+		assert.NoError(t, source.ExportNdjson("frog", &out))
+
+		dumpPath := tempDumpPath(t)
+		database, err := New(testContext(t), dumpPath, time.Hour, WithImportBatchSize(2))
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: frogSchema})
+		res, err := database.ImportNdjson("frog", &out)
+		assert.NoError(t, err)
+		assert.Equal(t, &ImportResult{Inserted: 5, Errors: []LineError{}}, res)
+		expected, err := source.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		imported, err := database.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, imported)
+
+		// Batches of 2, 2 and 1 rows are logged as separate inserts
+		inserts := 0
+		assert.NoError(t, database.wal.Replay(func(entry any) error {
+			if _, ok := entry.(*CommandInsert); ok {
+				inserts++
+			}
+			return nil
+		}))
+		assert.Equal(t, 3, inserts)
+	})
+	t.Run("skips invalid lines", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: frogSchema})
+		input := `{"name": "a", "sex": "m", "jump": [1, 2]}` + "\n" +
+			`{"name": "b", "sex": "m"` + "\n" +
+			"\n" +
+			`{"name": "c", "sex": "m", "jump": [3, 2]}` + "\n" +
+			`{"name": "d", "sex": "f", "jump": [1, 2], "age": 1}` + "\n" +
+			`{"name": "e", "sex": "f", "jump": [1, 2]}`
+		res, err := database.ImportNdjson("frog", strings.NewReader(input))
+		assert.NoError(t, err)
+		assert.Equal(t, uint(2), res.Inserted)
+		assert.Equal(t, uint(3), res.Skipped)
+		lines := []int{}
+		for _, lineErr := range res.Errors {
+			lines = append(lines, lineErr.Line)
+		}
+		assert.Equal(t, []int{2, 4, 5}, lines)
+		selectRes, err := database.Execute(&CommandSelect{"frog", &[]string{"name"}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Equal(t, []table.ColumnSet{{"name": "a"}, {"name": "e"}}, *selectRes)
+	})
+}
+
+// Test sql script export.
+func TestSql(t *testing.T) {
+	t.Run("creates tables and inserts rows", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{
+			"id": dbtypes.Integer, "leg_length": dbtypes.Real, "sex": dbtypes.Char,
+			"name": dbtypes.String, "jump": dbtypes.RealInv, "photo": dbtypes.Image}})
+		database.Execute(&CommandCreateTable{Name: "empty", Schema: schema.T{"id": dbtypes.Integer}})
+		database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{
+			{"id": 1, "leg_length": 1.5, "sex": "m", "name": "kermit's", "jump": []float64{2.5, 3}, "photo": "https://frog.png"},
+			{"id": -7, "leg_length": 0.25, "sex": "f", "name": "", "jump": []float64{-1, 0}, "photo": ""}}})
+		var out bytes.Buffer
+		assert.NoError(t, database.ExportSql(&out))
+		assert.Equal(t, `BEGIN;
+
+CREATE TABLE "empty" (
+  "id" BIGINT NOT NULL
+);
+
+CREATE TABLE "frog" (
+  "id" BIGINT NOT NULL,
+  "jump_from" DOUBLE PRECISION NOT NULL,
+  "jump_to" DOUBLE PRECISION NOT NULL,
+  "leg_length" DOUBLE PRECISION NOT NULL,
+  "name" TEXT NOT NULL,
+  "photo" TEXT NOT NULL,
+  "sex" CHAR(1) NOT NULL
+);
+INSERT INTO "frog" ("id", "jump_from", "jump_to", "leg_length", "name", "photo", "sex") VALUES
+(1, 2.5, 3, 1.5, 'kermit''s', 'https://frog.png', 'm'),
+(-7, -1, 0, 0.25, '', '', 'f');
+COMMIT;
+`, out.String())
+	})
+	t.Run("splits inserts to batches", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"id": dbtypes.Integer}})
+		rows := make([]table.ColumnSet, sqlBatchSize+1)
+		for i := range rows {
+			rows[i] = table.ColumnSet{"id": i}
+		}
+		database.Execute(&CommandInsert{"frog", &rows})
+		var out bytes.Buffer
+		assert.NoError(t, database.ExportSql(&out))
+		assert.Equal(t, 2, strings.Count(out.String(), "INSERT INTO"))
+		assert.Equal(t, sqlBatchSize+1, strings.Count(out.String(), "\n("))
+	})
+}
+
+// Test streaming load of dumps.
+func TestLoadDump(t *testing.T) {
+	t.Run("reports progress", func(t *testing.T) {
+		for _, format := range []DumpFormat{DumpJson, DumpBinary} {
+			dumpPath := tempDumpPath(t)
+			source, err := New(testContext(t), dumpPath, time.Hour, WithDumpFormat(format))
+			assert.NoError(t, err)
+			source.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"id": dbtypes.Integer}})
+			source.Execute(&CommandCreateTable{Name: "leg", Schema: schema.T{"id": dbtypes.Integer}})
+			rows := make([]table.ColumnSet, 5)
+			for i := range rows {
+				rows[i] = table.ColumnSet{"id": i}
+			}
+			source.Execute(&CommandInsert{"frog", &rows})
+			assert.NoError(t, source.StoreDump())
+
+			progress := []LoadProgress{}
+			database, err := New(testContext(t), tempDumpPath(t), time.Hour, WithImportBatchSize(2), WithLoadProgress(func(p LoadProgress) {
+				progress = append(progress, p)
+			}))
+			assert.NoError(t, err)
+			assert.NoError(t, database.FromDump(dumpPath))
+			assert.Equal(t, LoadProgress{Tables: 2, Rows: 5}, progress[len(progress)-1])
+			// Rows are reported by batches
+			rowCounts := []int{}
+			for _, p := range progress {
+				rowCounts = append(rowCounts, p.Rows)
+			}
+			assert.Subset(t, rowCounts, []int{2, 4, 5})
+			res, err := database.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+			assert.NoError(t, err)
+			assert.Len(t, *res, 5)
+		}
+	})
+	t.Run("accepts any order of table fields", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		dumpPath := filepath.Join(t.TempDir(), "old.json")
+		assert.NoError(t, os.WriteFile(dumpPath, []byte(`[
+			{"schema":{"id":"integer"},"data":[{"id":1},{"id":2}],"name":"frog"},
+			{"data":[{"id":3}],"comment":{"any":["value"]},"name":"leg","schema":{"id":"integer"}},
+			{"name":"empty","schema":{"id":"integer"},"data":null}
+		]`), 0644))
+		assert.NoError(t, database.FromDump(dumpPath))
+		for tableName, expected := range map[string][]table.ColumnSet{
+			"frog":  {{"id": int64(1)}, {"id": int64(2)}},
+			"leg":   {{"id": int64(3)}},
+			"empty": {},
+		} {
+			res, err := database.Execute(&CommandSelect{tableName, &[]string{}, table.ColumnSet{}})
+			assert.NoError(t, err)
+			assert.Equal(t, expected, *res)
+		}
+	})
+	t.Run("fails on invalid rows", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		dumpPath := filepath.Join(t.TempDir(), "invalid.json")
+		assert.NoError(t, os.WriteFile(dumpPath, []byte(`[{"schema":{"id":"integer"},"name":"frog","data":[{"id":1},{"id":"x"}]}]`), 0644))
+		var corrupted *errs.ErrCorruptedDump
+		assert.ErrorAs(t, database.FromDump(dumpPath), &corrupted)
+	})
+}
+
+// Test closing db.
+func TestClose(t *testing.T) {
+	t.Run("stores final dump", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"id": dbtypes.Integer}})
+		database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"id": 1}}})
+		assert.NoError(t, database.Close())
+		assert.NoError(t, database.Close())
+		info, err := os.Stat(walPath(dumpPath))
+		assert.NoError(t, err)
+		assert.Zero(t, info.Size())
+
+		restarted, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		res, err := restarted.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Equal(t, []table.ColumnSet{{"id": int64(1)}}, *res)
+	})
+	t.Run("rejects writes after close", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		assert.NoError(t, database.Close())
+		_, err = database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"id": dbtypes.Integer}})
+		assert.IsType(t, &errs.ErrDbIO{}, err)
+	})
+}
+
+// Test background jobs of db.
+func TestJobs(t *testing.T) {
+	t.Run("dump job is observable", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database, err := New(testContext(t), dumpPath, 10*time.Millisecond)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"id": dbtypes.Integer}})
+		assert.Eventually(t, func() bool {
+			jobs := database.Jobs()
+			return len(jobs) == 2 && jobs[0].Runs > 0
+		}, time.Second, 5*time.Millisecond)
+		job := database.Jobs()[0]
+		assert.Equal(t, dumpJob, job.Name)
+		assert.Equal(t, gcJob, database.Jobs()[1].Name)
+		assert.Equal(t, 10*time.Millisecond, job.Interval)
+		assert.Empty(t, job.LastError)
+		segmentPath(t, dumpPath, "frog")
+	})
+	t.Run("close stops jobs", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Millisecond)
+		assert.NoError(t, err)
+		assert.NoError(t, database.Close())
+		runs := database.Jobs()[0].Runs
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, runs, database.Jobs()[0].Runs)
+		assert.False(t, database.Jobs()[0].Running)
+	})
+	t.Run("cancelled context stops jobs", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		database, err := New(ctx, tempDumpPath(t), time.Millisecond)
+		assert.NoError(t, err)
+		cancel()
+		// Close waits for jobs, that are stopped already
+		assert.NoError(t, database.Close())
+		runs := database.Jobs()[0].Runs
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, runs, database.Jobs()[0].Runs)
+	})
+}
+
+// Test status of stored dumps.
+func TestDumpStatus(t *testing.T) {
+	t.Run("reports last dump", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		status, err := database.DumpStatus()
+		assert.NoError(t, err)
+		assert.Nil(t, status.Last)
+
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"id": dbtypes.Integer}})
+		assert.NoError(t, database.StoreDump())
+		status, err = database.DumpStatus()
+		assert.NoError(t, err)
+		assert.NotNil(t, status.Last)
+		assert.Equal(t, 1, status.Last.Tables)
+		assert.Positive(t, status.Last.Size)
+		assert.Zero(t, status.Failures)
+	})
+	t.Run("reports failed dumps", func(t *testing.T) {
+		dumpPath := tempDumpPath(t)
+		database, err := New(testContext(t), dumpPath, time.Hour)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"id": dbtypes.Integer}})
+		// Segments can not be written, while their directory is a file
+		assert.NoError(t, os.WriteFile(segmentsDir(dumpPath), nil, 0644))
+		assert.Error(t, database.StoreDump())
+		assert.NoError(t, os.Remove(segmentsDir(dumpPath)))
+
+		status, err := database.DumpStatus()
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), status.Failures)
+		assert.NotEmpty(t, status.LastError)
+		assert.False(t, status.LastErrorTime.IsZero())
+	})
+	t.Run("json dump restores tables", func(t *testing.T) {
+		database, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		database.Execute(&CommandCreateTable{Name: "frog", Schema: schema.T{"id": dbtypes.Integer}})
+		database.Execute(&CommandInsert{"frog", &[]table.ColumnSet{{"id": 1}}})
+		uploadPath := filepath.Join(t.TempDir(), "upload")
+		var payload []byte
+		for msg := range database.JsonDump() {
+			assert.NoError(t, msg.Err)
+			payload = append(payload, msg.Payload...)
+		}
+		assert.NoError(t, os.WriteFile(uploadPath, payload, 0644))
+
+		restored, err := New(testContext(t), tempDumpPath(t), time.Hour)
+		assert.NoError(t, err)
+		assert.NoError(t, restored.FromDump(uploadPath))
+		res, err := restored.Execute(&CommandSelect{"frog", &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.Equal(t, []table.ColumnSet{{"id": int64(1)}}, *res)
+	})
+}
+
+// Stress test of concurrent ddl, dml and dumps, run it with -race.
+func TestConcurrency(t *testing.T) {
+	const (
+		workers    = 4
+		iterations = 50
+	)
+	dumpPath := tempDumpPath(t)
+	database, err := New(testContext(t), dumpPath, time.Millisecond)
+	assert.NoError(t, err)
+	tableNames := []string{"frog", "toad"}
+	engines := []table.EngineKind{table.MemoryEngine, table.DiskEngine}
+	sch := schema.T{"id": dbtypes.Integer, "name": dbtypes.String}
+	// Dump to restore from exists before workers start
+	database.Execute(&CommandCreateTable{Name: tableNames[0], Schema: sch})
+	assert.NoError(t, database.StoreDump())
+
+	done := make(chan struct{})
+	run := func(job func(worker, i int)) {
+		for w := 0; w < workers; w++ {
+			go func(worker int) {
+				defer func() { done <- struct{}{} }()
+				for i := 0; i < iterations; i++ {
+					job(worker, i)
+				}
+			}(w)
+		}
+	}
+	// Errors are expected, tables come and go
+	run(func(worker, i int) {
+		name := tableNames[(worker+i)%len(tableNames)]
+		if i%5 == 4 {
+			database.Execute(&CommandDropTable{Name: name})
+			return
+		}
+		database.Execute(&CommandCreateTable{Name: name, Schema: sch, Engine: engines[worker%len(engines)]})
+	})
+	run(func(worker, i int) {
+		name := tableNames[i%len(tableNames)]
+		database.Execute(&CommandInsert{name, &[]table.ColumnSet{{"id": i, "name": "frog"}, {"id": i, "name": "frog"}}})
+		database.Execute(&CommandUpdate{TableName: name, Conditions: table.ColumnSet{"id": i}, Data: table.ColumnSet{"name": "toad"}})
+		database.Execute(&CommandSelect{name, &[]string{}, table.ColumnSet{}})
+		database.Execute(&CommandRemoveDuplicates{name})
+		database.Execute(&CommandDelete{From: name, Conditions: table.ColumnSet{"id": i - 1}})
+	})
+	run(func(worker, i int) {
+		switch (worker + i) % 5 {
+		case 0:
+			assert.NoError(t, database.StoreDump())
+		case 1:
+			for msg := range database.JsonDump() {
+				assert.NoError(t, msg.Err)
+			}
+		case 2:
+			_, err := database.IntrospectSchema()
+			assert.NoError(t, err)
+		case 3:
+			database.ExportCsv(tableNames[i%len(tableNames)], io.Discard)
+		case 4:
+			assert.NoError(t, database.FromDump(dumpPath))
+		}
+	})
+	for i := 0; i < 3*workers; i++ {
+		<-done
+	}
+
+	dbSchema, err := database.IntrospectSchema()
+	assert.NoError(t, err)
+	rows := map[string]*[]table.ColumnSet{}
+	for name := range dbSchema {
+		rows[name], err = database.Execute(&CommandSelect{name, &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, database.Close())
+	restarted, err := New(testContext(t), dumpPath, time.Hour)
+	assert.NoError(t, err)
+	restartedSchema, err := restarted.IntrospectSchema()
+	assert.NoError(t, err)
+	assert.Equal(t, dbSchema, restartedSchema)
+	for name := range dbSchema {
+		res, err := restarted.Execute(&CommandSelect{name, &[]string{}, table.ColumnSet{}})
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, *rows[name], *res)
+	}
+}
+
+// Path of table segment in incremental dump
+func segmentPath(t *testing.T, dumpPath string, tableName string) string {
+	m, err := readManifest(dumpPath)
+	assert.NoError(t, err)
+	for _, s := range m.Segments {
+		if s.Table == tableName {
+			return filepath.Join(filepath.Dir(dumpPath), s.File)
+		}
+	}
+	t.Fatalf("no segment of table %s in manifest", tableName)
+	return ""
+}
+
+// Context of test, background jobs of databases created with it
+// stop when test ends
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// Fresh dump path, so databases of different tests don't share dump and wal
+func tempDumpPath(t *testing.T) string {
+	return filepath.Join(t.TempDir(), "dump.json")
 }
@@ -0,0 +1,99 @@
+package dbtypes
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+type ByteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// Append binary encoding of parsed value to buf.
+// Integers and chars are varints, reals are little endian IEEE 754 numbers,
+// strings are prefixed with uvarint length.
+func AppendBinary(buf []byte, dataType Type, value any) ([]byte, error) {
+	switch dataType {
+	case Integer:
+		v, ok := value.(int64)
+		if !ok {
+			return nil, fmt.Errorf("unexpected value %v for integer", value)
+		}
+		return binary.AppendVarint(buf, v), nil
+	case Real:
+		v, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("unexpected value %v for real", value)
+		}
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v)), nil
+	case Char:
+		v, ok := value.(rune)
+		if !ok {
+			return nil, fmt.Errorf("unexpected value %v for char", value)
+		}
+		return binary.AppendVarint(buf, int64(v)), nil
+	case String, Image:
+		v, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected value %v for %s", value, dataType)
+		}
+		return append(binary.AppendUvarint(buf, uint64(len(v))), v...), nil
+	case RealInv:
+		v, ok := value.([]float64)
+		if !ok || len(v) != 2 {
+			return nil, fmt.Errorf("unexpected value %v for realInv", value)
+		}
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v[0]))
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v[1])), nil
+	default:
+		return nil, fmt.Errorf("%s is invalid data type", dataType)
+	}
+}
+
+// Read value encoded by AppendBinary
+func ReadBinary(r ByteReader, dataType Type) (any, error) {
+	switch dataType {
+	case Integer:
+		return binary.ReadVarint(r)
+	case Real:
+		return readFloat(r)
+	case Char:
+		v, err := binary.ReadVarint(r)
+		return rune(v), err
+	case String, Image:
+		length, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		// Read in chunks, so corrupted length doesn't allocate gigabytes upfront
+		var buf bytes.Buffer
+		if _, err := io.CopyN(&buf, r, int64(length)); err != nil {
+			return nil, err
+		}
+		return buf.String(), nil
+	case RealInv:
+		a, err := readFloat(r)
+		if err != nil {
+			return nil, err
+		}
+		b, err := readFloat(r)
+		if err != nil {
+			return nil, err
+		}
+		return []float64{a, b}, nil
+	default:
+		return nil, fmt.Errorf("%s is invalid data type", dataType)
+	}
+}
+
+func readFloat(r io.Reader) (float64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(buf[:])), nil
+}
@@ -0,0 +1,71 @@
+package dbtypes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	errs "github.com/ssyrota/frog-db/src/core/err"
+)
+
+// Format parsed value as text, ParseText restores it back.
+// Chars are written as symbols, realInv as "[from,to]".
+func FormatText(dataType Type, value any) (string, error) {
+	switch dataType {
+	case Integer:
+		v, ok := value.(int64)
+		if !ok {
+			return "", fmt.Errorf("unexpected value %v for integer", value)
+		}
+		return strconv.FormatInt(v, 10), nil
+	case Real:
+		v, ok := value.(float64)
+		if !ok {
+			return "", fmt.Errorf("unexpected value %v for real", value)
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case Char:
+		v, ok := value.(rune)
+		if !ok {
+			return "", fmt.Errorf("unexpected value %v for char", value)
+		}
+		return string(v), nil
+	case String, Image:
+		v, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("unexpected value %v for %s", value, dataType)
+		}
+		return v, nil
+	case RealInv:
+		v, ok := value.([]float64)
+		if !ok || len(v) != 2 {
+			return "", fmt.Errorf("unexpected value %v for realInv", value)
+		}
+		return "[" + strconv.FormatFloat(v[0], 'g', -1, 64) + "," + strconv.FormatFloat(v[1], 'g', -1, 64) + "]", nil
+	default:
+		return "", fmt.Errorf("%s is invalid data type", dataType)
+	}
+}
+
+// Parse value formatted by FormatText
+func ParseText(dataType Type, text string) (any, error) {
+	switch dataType {
+	case Integer:
+		// Leading zeros don't mean octal numbers in text
+		return strconv.ParseInt(text, 10, 64)
+	case Char:
+		runes := []rune(text)
+		if len(runes) != 1 {
+			return nil, fmt.Errorf("%s must contain exact 1 symbol", text)
+		}
+		return runes[0], nil
+	case RealInv:
+		bounds := strings.Split(strings.TrimSuffix(strings.TrimPrefix(text, "["), "]"), ",")
+		if len(bounds) != 2 || !strings.HasPrefix(text, "[") || !strings.HasSuffix(text, "]") {
+			return nil, errs.NewErrInvalidRangeDeclaration()
+		}
+		return NewRealInv([]any{strings.TrimSpace(bounds[0]), strings.TrimSpace(bounds[1])})
+	default:
+		return NewDataVal(dataType, text)
+	}
+}
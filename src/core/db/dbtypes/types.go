@@ -133,3 +133,46 @@ func NewRealInv(val any) ([]float64, error) {
 	}
 	return []float64{aVal, bVal}, nil
 }
+
+// Check if values of type are ordered, so they can be compared by Compare
+func IsOrdered(dataType Type) bool {
+	switch dataType {
+	case Integer, Real, Char, String:
+		return true
+	default:
+		return false
+	}
+}
+
+// Compare parsed values of ordered type, result is -1 if a < b,
+// 0 if a == b and 1 if a > b
+func Compare(dataType Type, a, b any) (int, error) {
+	switch dataType {
+	case Integer:
+		return compareAs[int64](dataType, a, b)
+	case Real:
+		return compareAs[float64](dataType, a, b)
+	case Char:
+		return compareAs[rune](dataType, a, b)
+	case String:
+		return compareAs[string](dataType, a, b)
+	default:
+		return 0, fmt.Errorf("%s values are not ordered", dataType)
+	}
+}
+
+func compareAs[T int64 | float64 | rune | string](dataType Type, a, b any) (int, error) {
+	typedA, okA := a.(T)
+	typedB, okB := b.(T)
+	if !okA || !okB {
+		return 0, fmt.Errorf("unexpected values %v, %v for %s", a, b, dataType)
+	}
+	switch {
+	case typedA < typedB:
+		return -1, nil
+	case typedA > typedB:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
@@ -34,3 +34,24 @@ func Map(m map[string]interface{}) (map[string]interface{}, error) {
 	}
 	return copy, nil
 }
+
+// Value performs a deep copy of the given value v, concrete types of
+// interface values must be registered with gob.Register.
+func Value(v any) (any, error) {
+	type wrapper struct {
+		V any
+	}
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	dec := gob.NewDecoder(&buf)
+	err := enc.Encode(&wrapper{v})
+	if err != nil {
+		return nil, err
+	}
+	var copy wrapper
+	err = dec.Decode(&copy)
+	if err != nil {
+		return nil, err
+	}
+	return copy.V, nil
+}
@@ -0,0 +1,511 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ssyrota/frog-db/src/core/db/codec"
+	"github.com/ssyrota/frog-db/src/core/db/schema"
+	"github.com/ssyrota/frog-db/src/core/db/snapshot"
+	"github.com/ssyrota/frog-db/src/core/db/table"
+	errs "github.com/ssyrota/frog-db/src/core/err"
+)
+
+type DumpFormat string
+
+const (
+	DumpJson   DumpFormat = "json"
+	DumpBinary DumpFormat = "binary"
+)
+
+func ParseDumpFormat(format string) (DumpFormat, error) {
+	switch DumpFormat(format) {
+	case DumpJson, DumpBinary:
+		return DumpFormat(format), nil
+	default:
+		return "", fmt.Errorf("unknown dump format %s", format)
+	}
+}
+
+// FromDump implementation.
+// Compression and format of the dump are detected from its header,
+// dump can be a single file, a manifest of incremental dump or
+// an identifier of stored dump.
+func (db *Database) FromDump(dumpPath string) error {
+	return db.restoreDump(db.resolveDump(dumpPath), true)
+}
+
+// FromDumpFile implementation.
+// Dump must be a single file, e.g. an uploaded one. Manifest is rejected,
+// as its segments would be read from directory of the file.
+func (db *Database) FromDumpFile(dumpPath string) error {
+	return db.restoreDump(dumpPath, false)
+}
+
+func (db *Database) restoreDump(dumpPath string, allowManifest bool) error {
+	db.dumpMu.Lock()
+	defer db.dumpMu.Unlock()
+	if !allowManifest {
+		manifest, err := isManifestFile(dumpPath)
+		if err != nil {
+			return err
+		}
+		if manifest {
+			return errs.NewErrCorruptedDump(dumpPath, fmt.Errorf("manifest is not accepted, single file dump is expected"))
+		}
+	}
+	// Read dump first, saving current data may prune it
+	tables, _, err := db.readDump(dumpPath)
+	if err != nil {
+		return err
+	}
+	// Save dump before delete data
+	if err := db.storeDump(context.Background()); err != nil {
+		closeTables(tables)
+		return err
+	}
+	// Replace tables only when whole dump is loaded. Loaded data is not
+	// covered by the wal, so it is checkpointed right away.
+	db.writeMu.Lock()
+	db.tablesMu.Lock()
+	replaced := db.tables
+	db.tables = tables
+	db.tablesMu.Unlock()
+	snap, err := db.snapshot()
+	db.writeMu.Unlock()
+	closeTables(replaced)
+	if err != nil {
+		return err
+	}
+	return db.storeSnapshot(context.Background(), snap)
+}
+
+// Release storage of tables, that are not used anymore
+func closeTables(tables map[string]*table.T) {
+	for tableName, t := range tables {
+		if err := t.Close(); err != nil {
+			log.Printf("close table %s: %s", tableName, err.Error())
+		}
+	}
+}
+
+// Read tables from dump and validate them against stored schemas,
+// manifest is returned if dump is incremental.
+func (db *Database) readDump(dumpPath string) (map[string]*table.T, *manifest, error) {
+	l := db.newLoader()
+	m, err := l.readDump(dumpPath)
+	if err != nil {
+		closeTables(l.tables)
+		return nil, nil, err
+	}
+	return l.tables, m, nil
+}
+
+func (l *loader) readDump(dumpPath string) (*manifest, error) {
+	manifest, err := isManifestFile(dumpPath)
+	if err != nil {
+		return nil, err
+	}
+	if !manifest {
+		return nil, l.readDumpFile(dumpPath, "")
+	}
+	m, err := readManifest(dumpPath)
+	var checksumErr *errs.ErrDumpChecksum
+	if errors.As(err, &checksumErr) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errs.NewErrCorruptedDump(dumpPath, err)
+	}
+	for i, segmentPath := range segmentPaths(dumpPath, m) {
+		s := m.Segments[i]
+		if err := l.readDumpFile(segmentPath, s.Checksum); err != nil {
+			return nil, err
+		}
+		if _, ok := l.tables[s.Table]; !ok {
+			return nil, errs.NewErrCorruptedDump(dumpPath, fmt.Errorf("segment %s has no table %s", s.File, s.Table))
+		}
+	}
+	return m, nil
+}
+
+// Read tables from single dump file. If checksum is set, it's verified
+// against file content before decoding, so corrupted file is never decoded
+// and is reported as checksum mismatch.
+func (l *loader) readDumpFile(dumpPath string, checksum string) error {
+	file, err := os.Open(dumpPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if checksum != "" {
+		hash := sha256.New()
+		if _, err := io.Copy(hash, file); err != nil {
+			return err
+		}
+		if hex.EncodeToString(hash.Sum(nil)) != checksum {
+			return errs.NewErrDumpChecksum(dumpPath, "segment")
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	return l.decodeDump(dumpPath, file)
+}
+
+// Decode dump of detected compression and format
+func (l *loader) decodeDump(dumpPath string, r io.Reader) error {
+	compressed := bufio.NewReader(r)
+	codecHeader, err := compressed.Peek(codec.MagicLen())
+	if err != nil && err != io.EOF {
+		return err
+	}
+	decoder, err := codec.Detect(codecHeader).NewReader(compressed)
+	if err != nil {
+		return errs.NewErrCorruptedDump(dumpPath, err)
+	}
+	defer decoder.Close()
+	reader := bufio.NewReader(decoder)
+	header, err := reader.Peek(len(snapshot.Magic))
+	if err != nil && err != io.EOF {
+		return errs.NewErrCorruptedDump(dumpPath, err)
+	}
+	if !snapshot.IsSnapshot(header) {
+		if err := l.loadJson(json.NewDecoder(reader)); err != nil {
+			return errs.NewErrCorruptedDump(dumpPath, err)
+		}
+		return nil
+	}
+	snapshotReader, err := snapshot.NewReader(reader)
+	if err != nil {
+		return errs.NewErrCorruptedDump(dumpPath, err)
+	}
+	err = l.loadSnapshot(snapshotReader)
+	if err == io.EOF {
+		return nil
+	}
+	if errors.Is(err, snapshot.ErrChecksum) {
+		return errs.NewErrDumpChecksum(dumpPath, err.Error())
+	}
+	return errs.NewErrCorruptedDump(dumpPath, err)
+}
+
+// StoreDump implementation.
+func (db *Database) StoreDump() error {
+	return db.StoreDumpContext(context.Background())
+}
+
+// StoreDumpContext implementation.
+// Writing stops once ctx is done, the previous dump stays the latest one
+// then. Cancelled dump is not counted as failure, timed out one is.
+func (db *Database) StoreDumpContext(ctx context.Context) error {
+	db.dumpMu.Lock()
+	defer db.dumpMu.Unlock()
+	err := contextErr(db.storeDump(ctx))
+	if !errors.Is(err, context.Canceled) {
+		db.dumpErrors.record(err)
+	}
+	return err
+}
+
+// Caller must hold dumpMu.
+func (db *Database) storeDump(ctx context.Context) error {
+	db.writeMu.Lock()
+	snap, err := db.snapshot()
+	db.writeMu.Unlock()
+	if err != nil {
+		return err
+	}
+	defer snap.release()
+	return db.storeSnapshot(ctx, snap)
+}
+
+// Point-in-time state of db, that is going to be dumped
+type dbSnapshot struct {
+	// Dumps of tables changed since the last stored dump
+	changed map[string]*table.Dump
+	// Tables by name with the last stored segment of unchanged ones
+	segments map[string]segment
+	// Size of wal, that is covered by snapshot
+	walOffset int64
+}
+
+// Release dumps of changed tables
+func (snap *dbSnapshot) release() {
+	for _, dump := range snap.changed {
+		dump.Release()
+	}
+}
+
+// Take snapshot of all tables at once. It's cheap, because dumped table
+// data is copied on write. Caller must hold writeMu and dumpMu.
+func (db *Database) snapshot() (*dbSnapshot, error) {
+	snap := &dbSnapshot{
+		changed:   make(map[string]*table.Dump),
+		segments:  make(map[string]segment, len(db.tables)),
+		walOffset: db.wal.Size(),
+	}
+	for tableName, t := range db.tables {
+		if s, ok := db.segments[tableName]; ok && s.table == t && s.version == t.Version() {
+			snap.segments[tableName] = s
+			continue
+		}
+		dump, err := t.Dump(tableName)
+		if err != nil {
+			snap.release()
+			return nil, err
+		}
+		snap.changed[tableName] = dump
+		snap.segments[tableName] = segment{table: t, version: dump.Version}
+	}
+	return snap, nil
+}
+
+// Write segments of changed tables and manifest, then discard the wal
+// records covered by snapshot. Idle db does no io.
+// Writers are not blocked meanwhile. Caller must hold dumpMu.
+func (db *Database) storeSnapshot(ctx context.Context, snap *dbSnapshot) error {
+	if len(snap.changed) != 0 || len(snap.segments) != len(db.segments) {
+		if err := os.MkdirAll(segmentsDir(db.path), 0755); err != nil {
+			return err
+		}
+		for tableName, dump := range snap.changed {
+			s := snap.segments[tableName]
+			s.file = db.segmentFile(tableName)
+			checksum, err := db.writeDumpFile(ctx, filepath.Join(filepath.Dir(db.path), s.file), []*table.Dump{dump})
+			if err != nil {
+				return err
+			}
+			s.checksum = checksum
+			snap.segments[tableName] = s
+		}
+		// Manifest is not written, if the last table was empty
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		m := &manifest{Manifest: manifestVersion, Segments: make([]manifestSegment, 0, len(snap.segments))}
+		for tableName, s := range snap.segments {
+			m.Segments = append(m.Segments, manifestSegment{Table: tableName, File: s.file, Checksum: s.checksum})
+		}
+		now := time.Now()
+		if err := writeHistory(db.path, m, now); err != nil {
+			return err
+		}
+		if err := writeManifest(db.path, m); err != nil {
+			return err
+		}
+		db.segments = snap.segments
+		if err := db.pruneDumps(now); err != nil {
+			return err
+		}
+	}
+	return db.wal.Discard(snap.walOffset)
+}
+
+// Write tables to dump file in configured format and codec,
+// returns checksum of file content. File is not written, if ctx is done
+// meanwhile.
+func (db *Database) writeDumpFile(ctx context.Context, path string, dumps []*table.Dump) (string, error) {
+	dumps = dumpsWithContext(ctx, dumps)
+	hash := sha256.New()
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		encoder, err := db.dumpCodec.NewWriter(io.MultiWriter(w, hash))
+		if err != nil {
+			return err
+		}
+		if db.dumpFormat == DumpBinary {
+			err = writeSnapshot(encoder, dumps)
+		} else {
+			err = writeJson(encoder, dumps)
+		}
+		if err != nil {
+			return err
+		}
+		return encoder.Close()
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Write dumps as json array, rows are encoded one by one,
+// so dump is never held in memory as a whole
+func writeJson(w io.Writer, dumps []*table.Dump) error {
+	out := bufio.NewWriter(w)
+	out.WriteString("[")
+	for i, dump := range dumps {
+		if i != 0 {
+			out.WriteString(",")
+		}
+		if err := writeJsonTable(out, dump); err != nil {
+			return err
+		}
+	}
+	out.WriteString("]")
+	return out.Flush()
+}
+
+func writeJsonTable(out *bufio.Writer, dump *table.Dump) error {
+	header := struct {
+		Schema schema.T         `json:"schema"`
+		Name   string           `json:"name"`
+		Engine table.EngineKind `json:"engine,omitempty"`
+	}{dump.Schema, dump.Name, dump.Engine}
+	raw, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	// Open header object and append data to it
+	out.Write(raw[:len(raw)-1])
+	out.WriteString(`,"data":[`)
+	first := true
+	err = dump.Scan(func(row table.ColumnSet) error {
+		raw, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		if !first {
+			out.WriteString(",")
+		}
+		first = false
+		_, err = out.Write(raw)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = out.WriteString("]}")
+	return err
+}
+
+func writeSnapshot(w io.Writer, dumps []*table.Dump) error {
+	writer, err := snapshot.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	for _, dump := range dumps {
+		if err := writer.WriteTable(dump); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// Dumps, that stop scan of rows once ctx is done
+func dumpsWithContext(ctx context.Context, dumps []*table.Dump) []*table.Dump {
+	res := make([]*table.Dump, len(dumps))
+	for i, dump := range dumps {
+		copied := *dump
+		if copied.Rows != nil {
+			copied.Rows = table.RowsWithContext(ctx, copied.Rows)
+		}
+		res[i] = &copied
+	}
+	return res
+}
+
+type DumpMsg struct {
+	Payload []byte
+	Err     error
+}
+
+// JsonDump implementation.
+func (db *Database) JsonDump() <-chan DumpMsg {
+	return db.JsonDumpContext(context.Background())
+}
+
+// JsonDumpContext implementation.
+// Payload is compressed with configured codec, dump reflects
+// state of all tables at the moment of call. Channel gets error of ctx
+// and is closed, once ctx is done.
+func (db *Database) JsonDumpContext(ctx context.Context) <-chan DumpMsg {
+	ch := make(chan DumpMsg)
+	dumps, dumpErr := db.dumpTables()
+	dumps = dumpsWithContext(ctx, dumps)
+	go func() {
+		defer close(ch)
+		defer releaseDumps(dumps)
+		if dumpErr != nil {
+			ch <- DumpMsg{nil, dumpErr}
+			return
+		}
+		encoder, err := db.dumpCodec.NewWriter(dumpMsgWriter(ch))
+		if err != nil {
+			ch <- DumpMsg{nil, err}
+			return
+		}
+		if err := writeJson(encoder, dumps); err != nil {
+			ch <- DumpMsg{nil, err}
+			return
+		}
+		if err := encoder.Close(); err != nil {
+			ch <- DumpMsg{nil, err}
+		}
+	}()
+	return ch
+}
+
+// Dumps of all tables at the same moment, sorted by table name
+func (db *Database) dumpTables() ([]*table.Dump, error) {
+	db.writeMu.Lock()
+	defer db.writeMu.Unlock()
+	dumps := make([]*table.Dump, 0, len(db.tables))
+	for tableName, t := range db.tables {
+		dump, err := t.Dump(tableName)
+		if err != nil {
+			releaseDumps(dumps)
+			return nil, err
+		}
+		dumps = append(dumps, dump)
+	}
+	sort.Slice(dumps, func(i, j int) bool { return dumps[i].Name < dumps[j].Name })
+	return dumps, nil
+}
+
+// Dump of table, that is not affected by further changes.
+// Caller releases dump after reading.
+func (db *Database) dumpTable(tableName string) (*table.Dump, error) {
+	t, err := db.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		dump, err := t.Dump(tableName)
+		if !errors.Is(err, table.ErrClosed) {
+			return dump, err
+		}
+		if t, err = db.reopenTable(tableName, t); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Release dumps of tables, that are read already
+func releaseDumps(dumps []*table.Dump) {
+	for _, dump := range dumps {
+		dump.Release()
+	}
+}
+
+// Sends every written chunk as dump message
+type dumpMsgWriter chan<- DumpMsg
+
+func (w dumpMsgWriter) Write(p []byte) (int, error) {
+	// Writers may reuse buffer after Write returns
+	payload := make([]byte, len(p))
+	copy(payload, p)
+	w <- DumpMsg{payload, nil}
+	return len(p), nil
+}
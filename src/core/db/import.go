@@ -0,0 +1,72 @@
+package db
+
+import "github.com/ssyrota/frog-db/src/core/db/table"
+
+// Line errors reported by import, the rest are only counted
+const maxImportErrors = 100
+
+type LineError struct {
+	Line    int
+	Message string
+}
+
+type ImportResult struct {
+	Inserted uint
+	// Lines skipped because of errors
+	Skipped uint
+	Errors  []LineError
+}
+
+// Set count of rows inserted at once by imports, 1000 by default
+func WithImportBatchSize(size int) Option {
+	return func(db *Database) {
+		if size > 0 {
+			db.importBatchSize = size
+		}
+	}
+}
+
+// Inserts imported rows to table in batches
+type importer struct {
+	db        *Database
+	tableName string
+	batch     []table.ColumnSet
+	result    *ImportResult
+}
+
+func (db *Database) newImporter(tableName string) *importer {
+	return &importer{
+		db:        db,
+		tableName: tableName,
+		batch:     make([]table.ColumnSet, 0, db.importBatchSize),
+		result:    &ImportResult{Errors: []LineError{}},
+	}
+}
+
+// Add row to batch, batch is inserted when it's full
+func (i *importer) add(row table.ColumnSet) error {
+	i.batch = append(i.batch, row)
+	if len(i.batch) < i.db.importBatchSize {
+		return nil
+	}
+	return i.flush()
+}
+
+func (i *importer) skip(line int, err error) {
+	i.result.Skipped++
+	if len(i.result.Errors) < maxImportErrors {
+		i.result.Errors = append(i.result.Errors, LineError{line, err.Error()})
+	}
+}
+
+func (i *importer) flush() error {
+	if len(i.batch) == 0 {
+		return nil
+	}
+	if _, err := i.db.Execute(&CommandInsert{To: i.tableName, Data: &i.batch}); err != nil {
+		return err
+	}
+	i.result.Inserted += uint(len(i.batch))
+	i.batch = i.batch[:0]
+	return nil
+}
@@ -0,0 +1,266 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+
+	dbtypes "github.com/ssyrota/frog-db/src/core/db/dbtypes"
+	"github.com/ssyrota/frog-db/src/core/db/schema"
+	"github.com/ssyrota/frog-db/src/core/db/snapshot"
+	"github.com/ssyrota/frog-db/src/core/db/table"
+	errs "github.com/ssyrota/frog-db/src/core/err"
+)
+
+type LoadProgress struct {
+	// Tables loaded completely
+	Tables int
+	// Rows loaded so far
+	Rows int
+}
+
+// Set callback, that is called while dump is loaded on start and by FromDump
+// after every batch of rows and every loaded table
+func WithLoadProgress(fn func(LoadProgress)) Option {
+	return func(db *Database) {
+		db.loadProgress = fn
+	}
+}
+
+// Streams tables of dump to new tables, rows are inserted in batches,
+// so dump is never held in memory as a whole
+type loader struct {
+	db       *Database
+	tables   map[string]*table.T
+	progress LoadProgress
+}
+
+func (db *Database) newLoader() *loader {
+	return &loader{db: db, tables: make(map[string]*table.T)}
+}
+
+// Table, that is being loaded
+type loadingTable struct {
+	loader *loader
+	header *table.Dump
+	t      *table.T
+	batch  []table.ColumnSet
+}
+
+func (l *loader) newLoadingTable() *loadingTable {
+	return &loadingTable{loader: l, header: &table.Dump{}}
+}
+
+// Add row, table is created on the first one
+func (lt *loadingTable) add(row table.ColumnSet) error {
+	if lt.t == nil {
+		if err := lt.create(); err != nil {
+			return err
+		}
+	}
+	lt.batch = append(lt.batch, row)
+	if len(lt.batch) < lt.loader.db.importBatchSize {
+		return nil
+	}
+	return lt.flush()
+}
+
+func (lt *loadingTable) create() error {
+	t, err := lt.loader.db.newTable(lt.header.Name, lt.header.Schema, lt.header.Engine)
+	if err != nil {
+		return err
+	}
+	lt.t = t
+	return nil
+}
+
+func (lt *loadingTable) flush() error {
+	if len(lt.batch) == 0 {
+		return nil
+	}
+	if _, err := lt.t.LoadRows(&lt.batch); err != nil {
+		return fmt.Errorf("table %s: %w", lt.header.Name, err)
+	}
+	lt.loader.progress.Rows += len(lt.batch)
+	lt.loader.report()
+	lt.batch = lt.batch[:0]
+	return nil
+}
+
+// Flush the rest of rows and add table to loaded ones
+func (lt *loadingTable) finish() error {
+	if lt.header.Name == "" {
+		return fmt.Errorf("table without name")
+	}
+	if _, ok := lt.loader.tables[lt.header.Name]; ok {
+		return errs.NewErrTableAlreadyExists(lt.header.Name)
+	}
+	if lt.t == nil {
+		if err := lt.create(); err != nil {
+			return err
+		}
+	}
+	for _, row := range lt.header.Data {
+		if err := lt.add(row); err != nil {
+			return err
+		}
+	}
+	if err := lt.flush(); err != nil {
+		return err
+	}
+	lt.loader.tables[lt.header.Name] = lt.t
+	lt.loader.progress.Tables++
+	lt.loader.report()
+	return nil
+}
+
+// Release table, if it was not loaded completely
+func (lt *loadingTable) abort() {
+	if lt.t != nil && lt.loader.tables[lt.header.Name] != lt.t {
+		lt.t.Close()
+	}
+}
+
+func (l *loader) report() {
+	if l.db.loadProgress != nil {
+		l.db.loadProgress(l.progress)
+	}
+}
+
+// Load json array of tables token by token
+func (l *loader) loadJson(decoder *json.Decoder) error {
+	if err := expectDelim(decoder, '['); err != nil {
+		return err
+	}
+	for decoder.More() {
+		lt := l.newLoadingTable()
+		if err := lt.loadJson(decoder); err != nil {
+			lt.abort()
+			return err
+		}
+	}
+	return expectDelim(decoder, ']')
+}
+
+func (lt *loadingTable) loadJson(decoder *json.Decoder) error {
+	if err := expectDelim(decoder, '{'); err != nil {
+		return err
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case "schema":
+			err = decoder.Decode(&lt.header.Schema)
+		case "name":
+			err = decoder.Decode(&lt.header.Name)
+		case "engine":
+			err = decoder.Decode(&lt.header.Engine)
+		case "data":
+			// Rows can't be validated without schema, they are kept then
+			if lt.header.Schema == nil {
+				err = decoder.Decode(&lt.header.Data)
+			} else {
+				err = lt.loadJsonRows(decoder)
+			}
+		default:
+			var skipped json.RawMessage
+			err = decoder.Decode(&skipped)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := expectDelim(decoder, '}'); err != nil {
+		return err
+	}
+	for _, row := range lt.header.Data {
+		if err := lt.restoreChars(row); err != nil {
+			return err
+		}
+	}
+	return lt.finish()
+}
+
+func (lt *loadingTable) restoreChars(row table.ColumnSet) error {
+	if err := restoreJsonChars(lt.header.Schema, row); err != nil {
+		return fmt.Errorf("table %s: %w", lt.header.Name, err)
+	}
+	return nil
+}
+
+// Json has no char type, runes are encoded as numbers and restored from them
+func restoreJsonChars(tableSchema schema.T, row table.ColumnSet) error {
+	for name, dataType := range tableSchema {
+		if dataType != dbtypes.Char {
+			continue
+		}
+		code, ok := row[name].(float64)
+		if !ok {
+			continue
+		}
+		if code != float64(rune(code)) {
+			return fmt.Errorf("%v is not a valid char code", code)
+		}
+		row[name] = rune(code)
+	}
+	return nil
+}
+
+func (lt *loadingTable) loadJsonRows(decoder *json.Decoder) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if token != json.Delim('[') {
+		return fmt.Errorf("unexpected %v instead of rows", token)
+	}
+	for decoder.More() {
+		var row table.ColumnSet
+		if err := decoder.Decode(&row); err != nil {
+			return err
+		}
+		if err := lt.restoreChars(row); err != nil {
+			return err
+		}
+		if err := lt.add(row); err != nil {
+			return err
+		}
+	}
+	return expectDelim(decoder, ']')
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("unexpected %v instead of %v", token, delim)
+	}
+	return nil
+}
+
+// Load tables of snapshot row by row
+func (l *loader) loadSnapshot(reader *snapshot.Reader) error {
+	for {
+		lt := l.newLoadingTable()
+		header, err := reader.NextRows(func(header *table.Dump, row table.ColumnSet) error {
+			lt.header = header
+			return lt.add(row)
+		})
+		if err != nil {
+			lt.abort()
+			return err
+		}
+		lt.header = header
+		if err := lt.finish(); err != nil {
+			lt.abort()
+			return err
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package db
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ssyrota/frog-db/src/core/db/table"
+	errs "github.com/ssyrota/frog-db/src/core/err"
+)
+
+// Incremental dump consists of a manifest stored by dump path and
+// segment files, each of them is a dump of a single table.
+// Segments are stored in a directory next to the manifest and are
+// rewritten only when their table changes.
+type manifest struct {
+	Manifest int               `json:"manifest"`
+	Segments []manifestSegment `json:"segments"`
+	// Checksum of segments list
+	Checksum string `json:"checksum"`
+}
+
+type manifestSegment struct {
+	Table string `json:"table"`
+	// File path relative to directory of manifest
+	File string `json:"file"`
+	// Checksum of segment file content
+	Checksum string `json:"checksum"`
+}
+
+const manifestVersion = 1
+
+var manifestPrefix = []byte(`{"manifest":`)
+
+// Segment of the last stored dump
+type segment struct {
+	table    *table.T
+	version  uint64
+	file     string
+	checksum string
+}
+
+// Check if header of dump file belongs to manifest
+func isManifest(header []byte) bool {
+	return bytes.HasPrefix(header, manifestPrefix)
+}
+
+// Check header of file, whether it is a manifest or a single file dump
+func isManifestFile(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	header := make([]byte, len(manifestPrefix))
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false, err
+	}
+	return isManifest(header[:n]), nil
+}
+
+func readManifest(path string) (*manifest, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	if m.Manifest != manifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version %d", m.Manifest)
+	}
+	checksum, err := segmentsChecksum(m.Segments)
+	if err != nil {
+		return nil, err
+	}
+	if checksum != m.Checksum {
+		return nil, errs.NewErrDumpChecksum(path, "manifest")
+	}
+	for _, s := range m.Segments {
+		// Segment without checksum would be loaded unverified
+		if s.Checksum == "" {
+			return nil, errs.NewErrDumpChecksum(path, fmt.Sprintf("segment %s has no checksum", s.Table))
+		}
+		if err := checkSegmentFile(filepath.Dir(path), s.File); err != nil {
+			return nil, err
+		}
+	}
+	return &m, nil
+}
+
+// Check that segment file path is relative and stays inside directory of
+// manifest, so uploaded manifest can't refer to arbitrary files
+func checkSegmentFile(dir string, file string) error {
+	if file == "" || filepath.IsAbs(file) || filepath.VolumeName(file) != "" {
+		return fmt.Errorf("segment file %q is not a relative path", file)
+	}
+	for _, part := range strings.FieldsFunc(file, isPathSeparator) {
+		if part == ".." {
+			return fmt.Errorf("segment file %q refers to parent directory", file)
+		}
+	}
+	rel, err := filepath.Rel(dir, filepath.Join(dir, file))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("segment file %q is outside of dump directory", file)
+	}
+	return nil
+}
+
+// Both separators are checked, because manifest may come from other os
+func isPathSeparator(r rune) bool {
+	return r == '/' || r == '\\'
+}
+
+func segmentsChecksum(segments []manifestSegment) (string, error) {
+	raw, err := json.Marshal(segments)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Paths of segment files of manifest
+func segmentPaths(manifestPath string, m *manifest) []string {
+	paths := make([]string, len(m.Segments))
+	for i, s := range m.Segments {
+		paths[i] = filepath.Join(filepath.Dir(manifestPath), s.File)
+	}
+	return paths
+}
+
+func writeManifest(path string, m *manifest) error {
+	checksum, err := segmentsChecksum(m.Segments)
+	if err != nil {
+		return err
+	}
+	m.Checksum = checksum
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(m)
+	})
+}
+
+// Directory of segments, that belong to dump
+func segmentsDir(dumpPath string) string {
+	return dumpPath + ".d"
+}
+
+// Unique segment file name relative to manifest directory
+func (db *Database) segmentFile(tableName string) string {
+	db.segmentSeq++
+	name := hex.EncodeToString([]byte(tableName)) + "-" + strconv.FormatUint(db.segmentSeq, 10) + ".seg"
+	return filepath.Join(filepath.Base(segmentsDir(db.path)), name)
+}
+
+// Remove files from segments directory except history manifests and
+// segments in use
+func removeUnusedSegments(dumpPath string, used map[string]bool) error {
+	entries, err := os.ReadDir(segmentsDir(dumpPath))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if used[entry.Name()] || isHistoryManifest(entry.Name()) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(segmentsDir(dumpPath), entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/ssyrota/frog-db/src/core/db/table"
+)
+
+// Write table rows as newline delimited json objects.
+// Rows reflect state of table at the moment of call.
+func (db *Database) ExportNdjson(tableName string, w io.Writer) error {
+	dump, err := db.dumpTable(tableName)
+	if err != nil {
+		return err
+	}
+	defer dump.Release()
+	out := bufio.NewWriter(w)
+	encoder := json.NewEncoder(out)
+	err = dump.Scan(func(row table.ColumnSet) error {
+		return encoder.Encode(table.WithoutVersion(row))
+	})
+	if err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// Import newline delimited json objects to table, empty lines are ignored.
+// Rows are inserted in batches, invalid lines are skipped and reported,
+// so import is not atomic: rows inserted before failure stay in table.
+func (db *Database) ImportNdjson(tableName string, r io.Reader) (*ImportResult, error) {
+	t, err := db.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+	tableSchema := t.Schema()
+	imp := db.newImporter(tableName)
+	in := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		raw, err := in.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return imp.result, err
+		}
+		if len(bytes.TrimSpace(raw)) != 0 {
+			var row table.ColumnSet
+			if jsonErr := json.Unmarshal(raw, &row); jsonErr != nil {
+				imp.skip(line, jsonErr)
+			} else if charErr := restoreJsonChars(tableSchema, row); charErr != nil {
+				imp.skip(line, charErr)
+			} else if typedRow, rowErr := table.ValidateRow(tableSchema, row); rowErr != nil {
+				imp.skip(line, rowErr)
+			} else if addErr := imp.add(typedRow); addErr != nil {
+				return imp.result, addErr
+			}
+		}
+		if err == io.EOF {
+			return imp.result, imp.flush()
+		}
+	}
+}
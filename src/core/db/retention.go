@@ -0,0 +1,154 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Every stored dump is kept as a timestamped manifest in segments
+// directory, manifests share segments of unchanged tables.
+const (
+	historyPrefix = "manifest-"
+	historySuffix = ".json"
+	// Layout of dump identifiers, they are sortable by time
+	dumpIDLayout = "20060102T150405.000000000Z"
+)
+
+// Which dumps to keep, the latest dump is always kept.
+// Dump is kept if it is one of Count latest dumps or it is younger than MaxAge.
+type Retention struct {
+	Count  int
+	MaxAge time.Duration
+}
+
+type DumpInfo struct {
+	ID   string
+	Time time.Time
+	// Size of manifest and all segments of dump in bytes
+	Size   int64
+	Tables int
+}
+
+// Set which dumps are kept by StoreDump, only the latest one by default
+func WithDumpRetention(retention Retention) Option {
+	return func(db *Database) {
+		db.retention = retention
+	}
+}
+
+func isHistoryManifest(name string) bool {
+	return strings.HasPrefix(name, historyPrefix) && strings.HasSuffix(name, historySuffix)
+}
+
+func historyPath(dumpPath string, id string) string {
+	return filepath.Join(segmentsDir(dumpPath), historyPrefix+id+historySuffix)
+}
+
+// Save copy of manifest to history, segment paths are made relative to
+// segments directory
+func writeHistory(dumpPath string, m *manifest, created time.Time) error {
+	history := &manifest{Manifest: m.Manifest, Segments: make([]manifestSegment, len(m.Segments))}
+	for i, s := range m.Segments {
+		history.Segments[i] = manifestSegment{Table: s.Table, File: filepath.Base(s.File), Checksum: s.Checksum}
+	}
+	return writeManifest(historyPath(dumpPath, created.UTC().Format(dumpIDLayout)), history)
+}
+
+// Identifiers of stored dumps, the latest first
+func historyIDs(dumpPath string) ([]string, error) {
+	entries, err := os.ReadDir(segmentsDir(dumpPath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, entry := range entries {
+		if isHistoryManifest(entry.Name()) {
+			ids = append(ids, strings.TrimSuffix(strings.TrimPrefix(entry.Name(), historyPrefix), historySuffix))
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	return ids, nil
+}
+
+// Remove dumps out of retention policy and segments no dump refers to.
+// Caller must hold dumpMu.
+func (db *Database) pruneDumps(now time.Time) error {
+	ids, err := historyIDs(db.path)
+	if err != nil {
+		return err
+	}
+	used := map[string]bool{}
+	for _, s := range db.segments {
+		used[filepath.Base(s.file)] = true
+	}
+	for i, id := range ids {
+		created, err := time.Parse(dumpIDLayout, id)
+		keep := i == 0 || i < db.retention.Count ||
+			(err == nil && db.retention.MaxAge > 0 && now.Sub(created) < db.retention.MaxAge)
+		if !keep {
+			if err := os.Remove(historyPath(db.path, id)); err != nil {
+				return err
+			}
+			continue
+		}
+		m, err := readManifest(historyPath(db.path, id))
+		if err != nil {
+			return fmt.Errorf("read dump %s: %w", id, err)
+		}
+		for _, s := range m.Segments {
+			used[s.File] = true
+		}
+	}
+	return removeUnusedSegments(db.path, used)
+}
+
+// ListDumps implementation.
+func (db *Database) ListDumps() ([]DumpInfo, error) {
+	db.dumpMu.Lock()
+	defer db.dumpMu.Unlock()
+	ids, err := historyIDs(db.path)
+	if err != nil {
+		return nil, err
+	}
+	dumps := make([]DumpInfo, 0, len(ids))
+	for _, id := range ids {
+		created, err := time.Parse(dumpIDLayout, id)
+		if err != nil {
+			return nil, fmt.Errorf("parse dump id %s: %w", id, err)
+		}
+		path := historyPath(db.path, id)
+		m, err := readManifest(path)
+		if err != nil {
+			return nil, fmt.Errorf("read dump %s: %w", id, err)
+		}
+		info := DumpInfo{ID: id, Time: created, Tables: len(m.Segments)}
+		for _, file := range append([]string{path}, segmentPaths(path, m)...) {
+			stat, err := os.Stat(file)
+			if err != nil {
+				return nil, fmt.Errorf("stat dump %s: %w", id, err)
+			}
+			info.Size += stat.Size()
+		}
+		dumps = append(dumps, info)
+	}
+	return dumps, nil
+}
+
+// Resolve dump identifier to path of its manifest, other references
+// are considered to be paths
+func (db *Database) resolveDump(ref string) string {
+	if _, err := os.Stat(ref); err == nil {
+		return ref
+	}
+	if _, err := os.Stat(historyPath(db.path, ref)); err == nil {
+		return historyPath(db.path, ref)
+	}
+	return ref
+}
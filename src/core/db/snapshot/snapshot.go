@@ -0,0 +1,354 @@
+// Package snapshot provides versioned binary format of db dump.
+//
+// Snapshot layout:
+//
+//	magic "FROGSNAP" | version uint16
+//	table section: 'T' | name | engine | columns count | (column | type tag)* | rows count | row* | section crc32
+//	row: version | values
+//	end marker 'E' | file crc32
+//
+// Strings are prefixed with uvarint length, counts are uvarints, integers
+// and chars are varints, reals and checksums are little endian numbers.
+// Row version is uvarint, values of a row are written in order of sorted
+// column names.
+// Section checksum covers section from its marker, file checksum covers
+// everything before it. Version 1 snapshots have no checksums, versions
+// before 3 have no storage engine of table, versions before 4 have no
+// row versions.
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"sort"
+
+	dbtypes "github.com/ssyrota/frog-db/src/core/db/dbtypes"
+	"github.com/ssyrota/frog-db/src/core/db/schema"
+	"github.com/ssyrota/frog-db/src/core/db/table"
+)
+
+const (
+	Magic   = "FROGSNAP"
+	Version = uint16(4)
+
+	tableMarker = 'T'
+	endMarker   = 'E'
+
+	// Limit of columns count of table, counts read from snapshot are
+	// untrusted, so they never size allocations
+	maxColumns = 1 << 16
+)
+
+var ErrChecksum = errors.New("checksum mismatch")
+
+var typeTags = map[dbtypes.Type]byte{
+	dbtypes.Integer: 1,
+	dbtypes.Real:    2,
+	dbtypes.Char:    3,
+	dbtypes.String:  4,
+	dbtypes.RealInv: 5,
+	dbtypes.Image:   6,
+}
+
+// Check if data starts with snapshot magic
+func IsSnapshot(header []byte) bool {
+	return bytes.HasPrefix(header, []byte(Magic))
+}
+
+type Writer struct {
+	out     *bufio.Writer
+	w       io.Writer
+	section hash.Hash32
+	file    hash.Hash32
+	buf     []byte
+}
+
+// Create writer and write snapshot header
+func NewWriter(w io.Writer) (*Writer, error) {
+	writer := &Writer{out: bufio.NewWriter(w), section: crc32.NewIEEE(), file: crc32.NewIEEE()}
+	writer.w = io.MultiWriter(writer.out, writer.section, writer.file)
+	if _, err := io.WriteString(writer.w, Magic); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(writer.w, binary.LittleEndian, Version); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// Write table section
+func (w *Writer) WriteTable(dump *table.Dump) error {
+	columns := sortedColumns(dump.Schema)
+	w.section.Reset()
+	w.writeByte(tableMarker)
+	w.writeString(dump.Name)
+	w.writeString(string(dump.Engine))
+	w.writeUvarint(uint64(len(columns)))
+	for _, column := range columns {
+		tag, ok := typeTags[dump.Schema[column]]
+		if !ok {
+			return fmt.Errorf("column %s has unknown type %s", column, dump.Schema[column])
+		}
+		w.writeString(column)
+		w.writeByte(tag)
+	}
+	w.writeUvarint(uint64(dump.Len()))
+	err := dump.Scan(func(row table.ColumnSet) error {
+		w.writeUvarint(uint64(table.RowVersion(row)))
+		for _, column := range columns {
+			var err error
+			w.buf, err = dbtypes.AppendBinary(w.buf[:0], dump.Schema[column], row[column])
+			if err != nil {
+				return fmt.Errorf("table %s, column %s: %w", dump.Name, column, err)
+			}
+			w.w.Write(w.buf)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return w.writeChecksum(w.section)
+}
+
+// Write end marker and flush snapshot
+func (w *Writer) Close() error {
+	w.writeByte(endMarker)
+	if err := w.writeChecksum(w.file); err != nil {
+		return err
+	}
+	return w.out.Flush()
+}
+
+// Write checksum, it is covered by file checksum only
+func (w *Writer) writeChecksum(h hash.Hash32) error {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], h.Sum32())
+	if _, err := w.out.Write(buf[:]); err != nil {
+		return err
+	}
+	_, err := w.file.Write(buf[:])
+	return err
+}
+
+func (w *Writer) writeByte(b byte) {
+	w.w.Write([]byte{b})
+}
+
+func (w *Writer) writeUvarint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	w.w.Write(buf[:binary.PutUvarint(buf[:], v)])
+}
+
+func (w *Writer) writeString(v string) {
+	w.writeUvarint(uint64(len(v)))
+	io.WriteString(w.w, v)
+}
+
+type Reader struct {
+	r       *hashingReader
+	version uint16
+}
+
+// Create reader and validate snapshot header
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{r: &hashingReader{bufio.NewReader(r), crc32.NewIEEE(), crc32.NewIEEE()}}
+	magic := make([]byte, len(Magic))
+	if _, err := io.ReadFull(reader.r, magic); err != nil {
+		return nil, noEOF(err)
+	}
+	if !IsSnapshot(magic) {
+		return nil, fmt.Errorf("invalid snapshot magic %q", magic)
+	}
+	var version uint16
+	if err := binary.Read(reader.r, binary.LittleEndian, &version); err != nil {
+		return nil, noEOF(err)
+	}
+	if version == 0 || version > Version {
+		return nil, fmt.Errorf("unsupported snapshot version %d", version)
+	}
+	reader.version = version
+	return reader, nil
+}
+
+// Read next table section passing its rows to onRow one by one instead of
+// collecting them, returns dump without data or io.EOF after the last
+// section. Section checksum is verified after all rows are read.
+func (r *Reader) NextRows(onRow func(header *table.Dump, row table.ColumnSet) error) (*table.Dump, error) {
+	r.r.section.Reset()
+	marker, err := r.r.ReadByte()
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	switch marker {
+	case endMarker:
+		if err := r.verifyChecksum(r.r.file); err != nil {
+			return nil, fmt.Errorf("file: %w", err)
+		}
+		return nil, io.EOF
+	case tableMarker:
+	default:
+		return nil, fmt.Errorf("unexpected section marker %q", marker)
+	}
+	dump, err := r.readHeader()
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if err := r.readRows(dump, onRow); err != nil {
+		return nil, noEOF(err)
+	}
+	if err := r.verifyChecksum(r.r.section); err != nil {
+		return nil, fmt.Errorf("table %s section: %w", dump.Name, err)
+	}
+	return dump, nil
+}
+
+// Read checksum and compare it with sum of data read so far
+func (r *Reader) verifyChecksum(h hash.Hash32) error {
+	if r.version < 2 {
+		return nil
+	}
+	expected := h.Sum32()
+	var buf [4]byte
+	if _, err := io.ReadFull(r.r.r, buf[:]); err != nil {
+		return noEOF(err)
+	}
+	r.r.file.Write(buf[:])
+	if binary.LittleEndian.Uint32(buf[:]) != expected {
+		return ErrChecksum
+	}
+	return nil
+}
+
+// Read table name, engine and schema
+func (r *Reader) readHeader() (*table.Dump, error) {
+	name, err := r.readString()
+	if err != nil {
+		return nil, err
+	}
+	var engine string
+	if r.version >= 3 {
+		if engine, err = r.readString(); err != nil {
+			return nil, err
+		}
+	}
+	columnsCount, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, err
+	}
+	if columnsCount > maxColumns {
+		return nil, fmt.Errorf("table %s has %d columns, limit is %d", name, columnsCount, maxColumns)
+	}
+	tableSchema := make(schema.T)
+	for i := uint64(0); i < columnsCount; i++ {
+		column, err := r.readString()
+		if err != nil {
+			return nil, err
+		}
+		tag, err := r.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		columnType, ok := typeByTag(tag)
+		if !ok {
+			return nil, fmt.Errorf("column %s has unknown type tag %d", column, tag)
+		}
+		tableSchema[column] = columnType
+	}
+	return &table.Dump{Name: name, Schema: tableSchema, Engine: table.EngineKind(engine)}, nil
+}
+
+func (r *Reader) readRows(dump *table.Dump, onRow func(header *table.Dump, row table.ColumnSet) error) error {
+	columns := sortedColumns(dump.Schema)
+	rowsCount, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return err
+	}
+	// Every row is read from data, so rows count can't exceed snapshot size.
+	// Rows without columns and version take no data, so their count is
+	// never trusted.
+	if len(columns) == 0 && r.version < 4 && rowsCount != 0 {
+		return fmt.Errorf("table %s has %d rows without columns", dump.Name, rowsCount)
+	}
+	for i := uint64(0); i < rowsCount; i++ {
+		row := make(table.ColumnSet, len(columns)+1)
+		if r.version >= 4 {
+			version, err := binary.ReadUvarint(r.r)
+			if err != nil {
+				return err
+			}
+			row[table.VersionColumn] = int64(version)
+		}
+		for _, column := range columns {
+			value, err := dbtypes.ReadBinary(r.r, dump.Schema[column])
+			if err != nil {
+				return err
+			}
+			row[column] = value
+		}
+		if err := onRow(dump, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Reader) readString() (string, error) {
+	value, err := dbtypes.ReadBinary(r.r, dbtypes.String)
+	if err != nil {
+		return "", err
+	}
+	return value.(string), nil
+}
+
+func typeByTag(tag byte) (dbtypes.Type, bool) {
+	for t, typeTag := range typeTags {
+		if typeTag == tag {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+func sortedColumns(sch schema.T) []string {
+	columns := table.MapKeys(sch)
+	sort.Strings(columns)
+	return columns
+}
+
+// Reader, that sums up section and file checksums of read data
+type hashingReader struct {
+	r       *bufio.Reader
+	section hash.Hash32
+	file    hash.Hash32
+}
+
+func (h *hashingReader) Read(p []byte) (int, error) {
+	n, err := h.r.Read(p)
+	h.section.Write(p[:n])
+	h.file.Write(p[:n])
+	return n, err
+}
+
+func (h *hashingReader) ReadByte() (byte, error) {
+	b, err := h.r.ReadByte()
+	if err == nil {
+		h.section.Write([]byte{b})
+		h.file.Write([]byte{b})
+	}
+	return b, err
+}
+
+// Snapshot always ends with end marker, so EOF means truncated data
+func noEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,153 @@
+package db
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	dbtypes "github.com/ssyrota/frog-db/src/core/db/dbtypes"
+	"github.com/ssyrota/frog-db/src/core/db/table"
+)
+
+// Rows per INSERT statement of sql export
+const sqlBatchSize = 500
+
+// Sql column types, that are understood by Postgres and SQLite
+var sqlTypes = map[dbtypes.Type]string{
+	dbtypes.Integer: "BIGINT",
+	dbtypes.Real:    "DOUBLE PRECISION",
+	dbtypes.Char:    "CHAR(1)",
+	dbtypes.String:  "TEXT",
+	dbtypes.Image:   "TEXT",
+}
+
+// Write sql script, that creates all tables and inserts their rows.
+// realInv column is split into <column>_from and <column>_to columns.
+// Script reflects state of db at the moment of call.
+func (db *Database) ExportSql(w io.Writer) error {
+	dumps, err := db.dumpTables()
+	if err != nil {
+		return err
+	}
+	defer releaseDumps(dumps)
+	out := bufio.NewWriter(w)
+	out.WriteString("BEGIN;\n")
+	for _, dump := range dumps {
+		if err := writeSqlTable(out, dump); err != nil {
+			return fmt.Errorf("table %s: %w", dump.Name, err)
+		}
+	}
+	out.WriteString("COMMIT;\n")
+	return out.Flush()
+}
+
+func writeSqlTable(out *bufio.Writer, dump *table.Dump) error {
+	columns := sortedColumns(dump.Schema)
+	sqlColumns := []string{}
+	definitions := []string{}
+	for _, column := range columns {
+		if dump.Schema[column] == dbtypes.RealInv {
+			for _, bound := range []string{"_from", "_to"} {
+				if _, ok := dump.Schema[column+bound]; ok {
+					return fmt.Errorf("column %s of realInv %s already exists", column+bound, column)
+				}
+				sqlColumns = append(sqlColumns, sqlIdentifier(column+bound))
+				definitions = append(definitions, sqlIdentifier(column+bound)+" DOUBLE PRECISION NOT NULL")
+			}
+			continue
+		}
+		sqlType, ok := sqlTypes[dump.Schema[column]]
+		if !ok {
+			return fmt.Errorf("%s is invalid data type", dump.Schema[column])
+		}
+		sqlColumns = append(sqlColumns, sqlIdentifier(column))
+		definitions = append(definitions, sqlIdentifier(column)+" "+sqlType+" NOT NULL")
+	}
+	fmt.Fprintf(out, "\nCREATE TABLE %s (\n  %s\n);\n", sqlIdentifier(dump.Name), strings.Join(definitions, ",\n  "))
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES\n", sqlIdentifier(dump.Name), strings.Join(sqlColumns, ", "))
+	batched := 0
+	values := make([]string, 0, len(sqlColumns))
+	err := dump.Scan(func(row table.ColumnSet) error {
+		values = values[:0]
+		for _, column := range columns {
+			var err error
+			values, err = appendSqlValues(values, dump.Schema[column], row[column])
+			if err != nil {
+				return fmt.Errorf("column %s: %w", column, err)
+			}
+		}
+		if batched == 0 {
+			out.WriteString(insert)
+		} else {
+			out.WriteString(",\n")
+		}
+		out.WriteString("(" + strings.Join(values, ", ") + ")")
+		batched++
+		if batched == sqlBatchSize {
+			out.WriteString(";\n")
+			batched = 0
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if batched != 0 {
+		out.WriteString(";\n")
+	}
+	return nil
+}
+
+// Append sql literals of value, realInv value is two literals
+func appendSqlValues(values []string, dataType dbtypes.Type, value any) ([]string, error) {
+	switch dataType {
+	case dbtypes.Real:
+		v, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("unexpected value %v for real", value)
+		}
+		return append(values, sqlFloat(v)), nil
+	case dbtypes.RealInv:
+		v, ok := value.([]float64)
+		if !ok || len(v) != 2 {
+			return nil, fmt.Errorf("unexpected value %v for realInv", value)
+		}
+		return append(values, sqlFloat(v[0]), sqlFloat(v[1])), nil
+	case dbtypes.Integer:
+		v, ok := value.(int64)
+		if !ok {
+			return nil, fmt.Errorf("unexpected value %v for integer", value)
+		}
+		return append(values, strconv.FormatInt(v, 10)), nil
+	default:
+		text, err := dbtypes.FormatText(dataType, value)
+		if err != nil {
+			return nil, err
+		}
+		return append(values, sqlString(text)), nil
+	}
+}
+
+func sqlFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "'NaN'"
+	case math.IsInf(v, 1):
+		return "'Infinity'"
+	case math.IsInf(v, -1):
+		return "'-Infinity'"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+func sqlString(v string) string {
+	return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+}
+
+func sqlIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
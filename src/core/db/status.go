@@ -0,0 +1,53 @@
+package db
+
+import (
+	"sync"
+	"time"
+)
+
+type DumpStatus struct {
+	// The latest stored dump, nil if there is none
+	Last *DumpInfo
+	// Failed dumps since start
+	Failures      uint64
+	LastError     string
+	LastErrorTime time.Time
+}
+
+// Errors of dumps since start
+type dumpErrors struct {
+	mu        sync.Mutex
+	failures  uint64
+	lastError string
+	lastTime  time.Time
+}
+
+func (e *dumpErrors) record(err error) {
+	if err == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.failures++
+	e.lastError = err.Error()
+	e.lastTime = time.Now()
+}
+
+// DumpStatus implementation.
+func (db *Database) DumpStatus() (DumpStatus, error) {
+	dumps, err := db.ListDumps()
+	if err != nil {
+		return DumpStatus{}, err
+	}
+	db.dumpErrors.mu.Lock()
+	status := DumpStatus{
+		Failures:      db.dumpErrors.failures,
+		LastError:     db.dumpErrors.lastError,
+		LastErrorTime: db.dumpErrors.lastTime,
+	}
+	db.dumpErrors.mu.Unlock()
+	if len(dumps) != 0 {
+		status.Last = &dumps[0]
+	}
+	return status, nil
+}
@@ -0,0 +1,129 @@
+// Package supervisor runs named background jobs of db, so they can be
+// observed and stopped together.
+package supervisor
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job does a piece of background work, it should return soon after ctx is done
+type Job func(ctx context.Context) error
+
+// Status of job
+type Status struct {
+	Name string
+	// Interval between runs, zero for jobs, that run once
+	Interval time.Duration
+	// Running is set while job is executed
+	Running  bool
+	Runs     uint64
+	Failures uint64
+	LastRun  time.Time
+	// Duration of the last run
+	LastDuration time.Duration
+	// Error of the last run, empty if it succeeded
+	LastError string
+}
+
+type Supervisor struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	jobs   map[string]*Status
+}
+
+// Create supervisor, jobs are stopped when ctx is done
+func New(ctx context.Context) *Supervisor {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Supervisor{ctx: ctx, cancel: cancel, jobs: make(map[string]*Status)}
+}
+
+// Run job every interval until supervisor is stopped
+func (s *Supervisor) Every(name string, interval time.Duration, job Job) {
+	s.start(name, interval, func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				s.run(name, job)
+			}
+		}
+	})
+}
+
+// Run job once in background
+func (s *Supervisor) Go(name string, job Job) {
+	s.start(name, 0, func() {
+		s.run(name, job)
+	})
+}
+
+func (s *Supervisor) start(name string, interval time.Duration, loop func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil {
+		return
+	}
+	s.jobs[name] = &Status{Name: name, Interval: interval}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		loop()
+	}()
+}
+
+func (s *Supervisor) run(name string, job Job) {
+	started := time.Now()
+	s.update(name, func(status *Status) {
+		status.Running = true
+		status.LastRun = started
+	})
+	err := job(s.ctx)
+	s.update(name, func(status *Status) {
+		status.Running = false
+		status.Runs++
+		status.LastDuration = time.Since(started)
+		status.LastError = ""
+		if err != nil {
+			status.Failures++
+			status.LastError = err.Error()
+		}
+	})
+	if err != nil {
+		log.Printf("job %s: %s", name, err.Error())
+	}
+}
+
+func (s *Supervisor) update(name string, fn func(status *Status)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.jobs[name])
+}
+
+// Statuses of jobs sorted by name
+func (s *Supervisor) Jobs() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]Status, 0, len(s.jobs))
+	for _, status := range s.jobs {
+		res = append(res, *status)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// Stop jobs and wait until they return
+func (s *Supervisor) Stop() {
+	s.mu.Lock()
+	s.cancel()
+	s.mu.Unlock()
+	s.wg.Wait()
+}
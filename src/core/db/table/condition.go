@@ -0,0 +1,308 @@
+package table
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	dbtypes "github.com/ssyrota/frog-db/src/core/db/dbtypes"
+	"github.com/ssyrota/frog-db/src/core/db/schema"
+	errs "github.com/ssyrota/frog-db/src/core/err"
+)
+
+// Operator of comparison of column value with operand
+type Operator string
+
+const (
+	Eq Operator = "eq"
+	Ne Operator = "ne"
+	Gt Operator = "gt"
+	// Greater or equal
+	Gte Operator = "gte"
+	Lt  Operator = "lt"
+	// Less or equal
+	Lte Operator = "lte"
+	// Operand is a list of values
+	In    Operator = "in"
+	NotIn Operator = "notIn"
+	// Operand is a list of lower and upper bounds, both are included
+	Between Operator = "between"
+)
+
+// Operators, that need values of ordered type
+var orderedOperators = []Operator{Gt, Gte, Lt, Lte, Between}
+
+// Keys of raw condition, that compose nested conditions. They are reserved,
+// so they are never column names.
+const (
+	// List of conditions, at least one of them must match
+	AnyOf = "$anyOf"
+	// List of conditions, all of them must match
+	AllOf = "$allOf"
+	// Condition, that must not match
+	Not = "$not"
+)
+
+// Condition, that row matches, if it matches every comparison and
+// every nested condition.
+//
+// Raw condition maps column names to values, that columns must be equal to,
+// or to comparisons, that map operators to operands. Nested conditions are
+// raw conditions too:
+//
+//	{"name": "frog", "leg_length": {"gt": 3.5, "lte": 10}, "id": {"in": [1, 2]}}
+//	{"$anyOf": [{"species": "tree"}, {"jump": {"gt": 2}, "weight": {"lt": 1}}]}
+type Condition struct {
+	comparisons []comparison
+	allOf       []Condition
+	// nil if condition has no AnyOf
+	anyOf []Condition
+	not   *Condition
+}
+
+type comparison struct {
+	column     string
+	columnType dbtypes.Type
+	operator   Operator
+	// Parsed operand, list of parsed values for in, notIn and between
+	operand any
+}
+
+// Parse raw condition with all nested ones, operands are converted to column
+// types of schema. Empty condition matches all rows.
+func ParseCondition(sch schema.T, raw ColumnSet) (Condition, error) {
+	// Columns are sorted, so the first invalid one is reported the same way
+	columns := MapKeys(raw)
+	sort.Strings(columns)
+	condition := Condition{comparisons: make([]comparison, 0, len(raw))}
+	for _, column := range columns {
+		switch column {
+		case AnyOf, AllOf:
+			nested, err := parseConditions(sch, column, raw[column])
+			if err != nil {
+				return Condition{}, err
+			}
+			if column == AnyOf {
+				condition.anyOf = nested
+			} else {
+				condition.allOf = nested
+			}
+			continue
+		case Not:
+			rawNested, ok := rawCondition(raw[column])
+			if !ok {
+				return Condition{}, errs.NewErrInvalidConditionKey(column, "needs condition")
+			}
+			nested, err := ParseCondition(sch, rawNested)
+			if err != nil {
+				return Condition{}, err
+			}
+			condition.not = &nested
+			continue
+		}
+		columnType, ok := sch[column]
+		if !ok {
+			return Condition{}, errs.NewErrColumnsNotFound([]string{column})
+		}
+		operators, ok := rawCondition(raw[column])
+		if !ok {
+			operand, err := dbtypes.NewDataVal(columnType, raw[column])
+			if err != nil {
+				return Condition{}, err
+			}
+			condition.comparisons = append(condition.comparisons, comparison{column, columnType, Eq, operand})
+			continue
+		}
+		if len(operators) == 0 {
+			return Condition{}, errs.NewErrInvalidCondition(column, "no operators")
+		}
+		names := MapKeys(operators)
+		sort.Strings(names)
+		for _, name := range names {
+			c, err := parseComparison(column, columnType, Operator(name), operators[name])
+			if err != nil {
+				return Condition{}, err
+			}
+			condition.comparisons = append(condition.comparisons, c)
+		}
+	}
+	return condition, nil
+}
+
+// Parse non empty list of raw conditions of key
+func parseConditions(sch schema.T, key string, raw any) ([]Condition, error) {
+	list := reflect.ValueOf(raw)
+	if list.Kind() != reflect.Slice || list.Len() == 0 {
+		return nil, errs.NewErrInvalidConditionKey(key, "needs non empty list of conditions")
+	}
+	conditions := make([]Condition, list.Len())
+	for i := range conditions {
+		rawNested, ok := rawCondition(list.Index(i).Interface())
+		if !ok {
+			return nil, errs.NewErrInvalidConditionKey(key, fmt.Sprintf("item %d is not a condition", i))
+		}
+		nested, err := ParseCondition(sch, rawNested)
+		if err != nil {
+			return nil, err
+		}
+		conditions[i] = nested
+	}
+	return conditions, nil
+}
+
+// Raw condition or operators of raw comparison, ok is false if value is
+// neither of them
+func rawCondition(value any) (map[string]any, bool) {
+	switch typed := value.(type) {
+	case map[string]any:
+		return typed, true
+	case ColumnSet:
+		return typed, true
+	default:
+		return nil, false
+	}
+}
+
+func parseComparison(column string, columnType dbtypes.Type, operator Operator, rawOperand any) (comparison, error) {
+	c := comparison{column: column, columnType: columnType, operator: operator}
+	for _, ordered := range orderedOperators {
+		if operator == ordered && !dbtypes.IsOrdered(columnType) {
+			return c, errs.NewErrInvalidCondition(column, fmt.Sprintf("operator %s is not supported by type %s", operator, columnType))
+		}
+	}
+	switch operator {
+	case Eq, Ne, Gt, Gte, Lt, Lte:
+		operand, err := dbtypes.NewDataVal(columnType, rawOperand)
+		if err != nil {
+			return c, err
+		}
+		c.operand = operand
+	case In, NotIn:
+		operand, err := parseList(columnType, rawOperand)
+		if err != nil {
+			return c, err
+		}
+		if operand == nil {
+			return c, errs.NewErrInvalidCondition(column, fmt.Sprintf("operator %s needs list of values", operator))
+		}
+		c.operand = operand
+	case Between:
+		operand, err := parseList(columnType, rawOperand)
+		if err != nil {
+			return c, err
+		}
+		if len(operand) != 2 {
+			return c, errs.NewErrInvalidCondition(column, "operator between needs list of lower and upper bounds")
+		}
+		order, err := dbtypes.Compare(columnType, operand[0], operand[1])
+		if err != nil {
+			return c, err
+		}
+		if order > 0 {
+			return c, errs.NewErrInvalidCondition(column, "lower bound of between is greater than upper one")
+		}
+		c.operand = operand
+	default:
+		return c, errs.NewErrInvalidCondition(column, fmt.Sprintf("unknown operator %s", operator))
+	}
+	return c, nil
+}
+
+// Parse list of values of type, nil if raw value is not a list
+func parseList(dataType dbtypes.Type, raw any) ([]any, error) {
+	value := reflect.ValueOf(raw)
+	if value.Kind() != reflect.Slice {
+		return nil, nil
+	}
+	list := make([]any, value.Len())
+	for i := range list {
+		parsed, err := dbtypes.NewDataVal(dataType, value.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		list[i] = parsed
+	}
+	return list, nil
+}
+
+// Check if row matches condition
+func (c Condition) Match(row ColumnSet) (bool, error) {
+	for _, comparison := range c.comparisons {
+		ok, err := comparison.match(row[comparison.column])
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	for _, nested := range c.allOf {
+		ok, err := nested.Match(row)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	if c.anyOf != nil {
+		ok, err := matchAny(c.anyOf, row)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	if c.not != nil {
+		ok, err := c.not.Match(row)
+		if err != nil || ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchAny(conditions []Condition, row ColumnSet) (bool, error) {
+	for _, nested := range conditions {
+		ok, err := nested.Match(row)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func (c comparison) match(value any) (bool, error) {
+	switch c.operator {
+	case Eq:
+		return reflect.DeepEqual(value, c.operand), nil
+	case Ne:
+		return !reflect.DeepEqual(value, c.operand), nil
+	case In, NotIn:
+		found := false
+		for _, item := range c.operand.([]any) {
+			if reflect.DeepEqual(value, item) {
+				found = true
+				break
+			}
+		}
+		return found == (c.operator == In), nil
+	case Between:
+		bounds := c.operand.([]any)
+		lower, err := dbtypes.Compare(c.columnType, value, bounds[0])
+		if err != nil {
+			return false, err
+		}
+		upper, err := dbtypes.Compare(c.columnType, value, bounds[1])
+		if err != nil {
+			return false, err
+		}
+		return lower >= 0 && upper <= 0, nil
+	}
+	order, err := dbtypes.Compare(c.columnType, value, c.operand)
+	if err != nil {
+		return false, err
+	}
+	switch c.operator {
+	case Gt:
+		return order > 0, nil
+	case Gte:
+		return order >= 0, nil
+	case Lt:
+		return order < 0, nil
+	default:
+		return order <= 0, nil
+	}
+}
@@ -0,0 +1,430 @@
+package table
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	dbtypes "github.com/ssyrota/frog-db/src/core/db/dbtypes"
+	"github.com/ssyrota/frog-db/src/core/db/schema"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	pageSize = 4096
+	// Pages of file kept in memory
+	cachedPages = 256
+	// File is compacted when garbage takes more than half of it
+	// and file is not smaller than compactMinSize
+	compactMinSize = 1 << 20
+)
+
+// Location of row record in file
+type record struct {
+	offset int64
+	size   int64
+}
+
+// Engine, that keeps rows in append-only paged file, so table may be larger
+// than RAM. Memory holds locations of rows and a bounded cache of pages.
+// Record is [uvarint length][uvarint row version][values of sorted columns]. Updated row is
+// appended as a new record, space of replaced and deleted records is
+// reclaimed by compaction.
+// Copy of table shares file with its source, both of them append to it,
+// until compaction moves live records of one of them to its own file.
+type diskEngine struct {
+	path    string
+	schema  schema.T
+	columns []string
+	file    *pagedFile
+	records []record
+	// Size of records of engine, the rest of file is garbage
+	live int64
+	// shared is set when records are referenced by snapshot or copy,
+	// records are copied on write then
+	shared bool
+	// File is removed, so it must not be compacted
+	closed bool
+}
+
+// Create engine with empty file by path. File is a storage of table
+// only while engine is open, it's not recovered after restart.
+func NewDiskEngine(path string, sch schema.T) (Engine, error) {
+	file, err := createPagedFile(path)
+	if err != nil {
+		return nil, err
+	}
+	columns := MapKeys(sch)
+	sort.Strings(columns)
+	return &diskEngine{path: path, schema: sch, columns: columns, file: file}, nil
+}
+
+func (e *diskEngine) Kind() EngineKind {
+	return DiskEngine
+}
+
+func (e *diskEngine) Len() int {
+	return len(e.records)
+}
+
+func (e *diskEngine) Scan(fn func(id int, row ColumnSet) error) error {
+	for i, rec := range e.records {
+		row, err := e.readRow(e.file, rec)
+		if err != nil {
+			return err
+		}
+		if err := fn(i, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshot has no spare capacity of records, so appending never touches it
+func (e *diskEngine) Insert(rows []ColumnSet) error {
+	var buf []byte
+	records := make([]record, len(rows))
+	for i, row := range rows {
+		start := len(buf)
+		var err error
+		buf, err = e.appendRecord(buf, row)
+		if err != nil {
+			return err
+		}
+		records[i] = record{int64(start), int64(len(buf) - start)}
+	}
+	offset, err := e.file.append(buf)
+	if err != nil {
+		return err
+	}
+	for i := range records {
+		records[i].offset += offset
+	}
+	e.records = append(e.records, records...)
+	e.live += int64(len(buf))
+	return nil
+}
+
+func (e *diskEngine) UpdateByID(id int, row ColumnSet) error {
+	buf, err := e.appendRecord(nil, row)
+	if err != nil {
+		return err
+	}
+	offset, err := e.file.append(buf)
+	if err != nil {
+		return err
+	}
+	if e.shared {
+		e.records = slices.Clone(e.records)
+		e.shared = false
+	}
+	e.live += int64(len(buf)) - e.records[id].size
+	e.records[id] = record{offset, int64(len(buf))}
+	return e.maybeCompact()
+}
+
+func (e *diskEngine) DeleteByID(ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	for _, id := range ids {
+		e.live -= e.records[id].size
+	}
+	e.records = removeIndexes(e.records, ids)
+	e.shared = false
+	return e.maybeCompact()
+}
+
+// Snapshot keeps file open until it is released
+func (e *diskEngine) Snapshot() (Rows, error) {
+	if e.closed {
+		return nil, ErrClosed
+	}
+	e.file.retain()
+	e.shared = true
+	return &diskRows{engine: e, file: e.file, records: e.records[:len(e.records):len(e.records)]}, nil
+}
+
+// Remove file of engine. Descriptor is closed, once the last snapshot
+// reading it is released. Compaction replaces file the same way.
+func (e *diskEngine) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	err := os.Remove(e.path)
+	if releaseErr := e.file.release(); err == nil {
+		err = releaseErr
+	}
+	return err
+}
+
+// Compact file, if at least quarter of it is garbage. Writes compact it
+// only when half of it is garbage, so garbage of tables, that are not
+// written anymore, is reclaimed here.
+func (e *diskEngine) Vacuum() error {
+	size := e.file.written()
+	garbage := size - e.live
+	if e.closed || garbage == 0 || garbage*4 < size {
+		return nil
+	}
+	return e.compact()
+}
+
+func (e *diskEngine) maybeCompact() error {
+	size := e.file.written()
+	if size < compactMinSize || (size-e.live)*2 < size {
+		return nil
+	}
+	return e.compact()
+}
+
+// Share file and records of snapshot of other engine with the same schema
+// instead of copying them, engine must be empty. Records are copied on
+// write, file is shared until compaction.
+func (e *diskEngine) share(rows *diskRows) error {
+	rows.file.retain()
+	replaced := e.file
+	e.file = rows.file
+	e.records = rows.records
+	e.shared = true
+	e.live = 0
+	for _, rec := range e.records {
+		e.live += rec.size
+	}
+	return replaced.release()
+}
+
+// Rewrite live records to new file and replace file of engine with it
+func (e *diskEngine) compact() error {
+	compactPath := e.path + ".compact"
+	file, err := createPagedFile(compactPath)
+	if err != nil {
+		return err
+	}
+	records := make([]record, len(e.records))
+	buf := make([]byte, 0, 16*pageSize)
+	for i, rec := range e.records {
+		raw := make([]byte, rec.size)
+		if _, err := e.file.ReadAt(raw, rec.offset); err != nil {
+			file.release()
+			return err
+		}
+		records[i] = record{file.size + int64(len(buf)), rec.size}
+		buf = append(buf, raw...)
+		if len(buf) >= 16*pageSize {
+			if _, err := file.append(buf); err != nil {
+				file.release()
+				return err
+			}
+			buf = buf[:0]
+		}
+	}
+	if _, err := file.append(buf); err != nil {
+		file.release()
+		return err
+	}
+	if err := os.Rename(compactPath, e.path); err != nil {
+		file.release()
+		return err
+	}
+	// Snapshots keep reading replaced file until they are released
+	replaced := e.file
+	e.file = file
+	e.records = records
+	e.shared = false
+	return replaced.release()
+}
+
+func (e *diskEngine) appendRecord(buf []byte, row ColumnSet) ([]byte, error) {
+	payload := binary.AppendUvarint(nil, uint64(RowVersion(row)))
+	for _, column := range e.columns {
+		var err error
+		payload, err = dbtypes.AppendBinary(payload, e.schema[column], row[column])
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", column, err)
+		}
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(payload)))
+	return append(buf, payload...), nil
+}
+
+func (e *diskEngine) readRow(file *pagedFile, rec record) (ColumnSet, error) {
+	raw := make([]byte, rec.size)
+	if _, err := file.ReadAt(raw, rec.offset); err != nil {
+		return nil, err
+	}
+	length, n := binary.Uvarint(raw)
+	if n <= 0 || int64(n)+int64(length) != rec.size {
+		return nil, fmt.Errorf("corrupted record at offset %d of %s", rec.offset, e.path)
+	}
+	r := bytes.NewReader(raw[n:])
+	version, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("record at offset %d of %s: %w", rec.offset, e.path, err)
+	}
+	row := make(ColumnSet, len(e.columns)+1)
+	row[VersionColumn] = int64(version)
+	for _, column := range e.columns {
+		value, err := dbtypes.ReadBinary(r, e.schema[column])
+		if err != nil {
+			return nil, fmt.Errorf("record at offset %d of %s: %w", rec.offset, e.path, err)
+		}
+		row[column] = value
+	}
+	return row, nil
+}
+
+type diskRows struct {
+	engine   *diskEngine
+	file     *pagedFile
+	records  []record
+	released atomic.Bool
+}
+
+func (r *diskRows) Len() int {
+	return len(r.records)
+}
+
+func (r *diskRows) Scan(fn func(row ColumnSet) error) error {
+	for _, rec := range r.records {
+		row, err := r.engine.readRow(r.file, rec)
+		if err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rows are released once, the next calls do nothing
+func (r *diskRows) Release() {
+	if r.released.CompareAndSwap(false, true) {
+		if err := r.file.release(); err != nil {
+			log.Printf("release snapshot of %s: %s", r.engine.path, err.Error())
+		}
+	}
+}
+
+// Append-only file, that is read by pages through bounded cache
+type pagedFile struct {
+	file *os.File
+	size int64
+	mu   sync.Mutex
+	// Cached pages and their numbers in order of caching
+	pages map[int64][]byte
+	order []int64
+	// References of engine and snapshots, file is closed, when
+	// the last of them is released
+	refs int
+}
+
+// Create file referenced by caller
+func createPagedFile(path string) (*pagedFile, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &pagedFile{file: file, pages: make(map[int64][]byte), refs: 1}, nil
+}
+
+// Add reference to file, caller must hold one already
+func (f *pagedFile) retain() {
+	f.mu.Lock()
+	f.refs++
+	f.mu.Unlock()
+}
+
+// Drop reference to file and close it, if it was the last one
+func (f *pagedFile) release() error {
+	f.mu.Lock()
+	f.refs--
+	last := f.refs == 0
+	f.mu.Unlock()
+	if !last {
+		return nil
+	}
+	return f.file.Close()
+}
+
+// Append data and get its offset. File may be shared by engines,
+// so appends are serialized.
+func (f *pagedFile) append(data []byte) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	offset := f.size
+	if len(data) == 0 {
+		return offset, nil
+	}
+	if _, err := f.file.WriteAt(data, offset); err != nil {
+		return 0, err
+	}
+	f.size += int64(len(data))
+	return offset, nil
+}
+
+// Size of appended data
+func (f *pagedFile) written() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.size
+}
+
+// ReadAt implementation.
+func (f *pagedFile) ReadAt(p []byte, off int64) (int, error) {
+	read := 0
+	for read < len(p) {
+		pageNo := (off + int64(read)) / pageSize
+		page, err := f.page(pageNo)
+		if err != nil {
+			return read, err
+		}
+		start := int(off + int64(read) - pageNo*pageSize)
+		if start >= len(page) {
+			return read, io.ErrUnexpectedEOF
+		}
+		read += copy(p[read:], page[start:])
+	}
+	return read, nil
+}
+
+func (f *pagedFile) page(pageNo int64) ([]byte, error) {
+	f.mu.Lock()
+	page, ok := f.pages[pageNo]
+	f.mu.Unlock()
+	if ok {
+		return page, nil
+	}
+	page = make([]byte, pageSize)
+	n, err := f.file.ReadAt(page, pageNo*pageSize)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	// The last page grows on append, so only full pages are cached
+	if n < pageSize {
+		return page[:n], nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// Page may be read while other engine appends to it
+	if (pageNo+1)*pageSize > f.size {
+		return page, nil
+	}
+	if _, ok := f.pages[pageNo]; !ok {
+		if len(f.order) == cachedPages {
+			delete(f.pages, f.order[0])
+			f.order = f.order[1:]
+		}
+		f.pages[pageNo] = page
+		f.order = append(f.order, pageNo)
+	}
+	return page, nil
+}
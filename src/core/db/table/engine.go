@@ -0,0 +1,78 @@
+package table
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+type EngineKind string
+
+const (
+	MemoryEngine EngineKind = "memory"
+	DiskEngine   EngineKind = "disk"
+)
+
+// Parse engine kind, empty kind is memory engine
+func ParseEngineKind(kind string) (EngineKind, error) {
+	switch EngineKind(kind) {
+	case "", MemoryEngine:
+		return MemoryEngine, nil
+	case DiskEngine:
+		return DiskEngine, nil
+	default:
+		return "", fmt.Errorf("unknown storage engine %s", kind)
+	}
+}
+
+// Engine stores rows of table. Row id is a position of row in scan order,
+// ids are valid until rows are deleted. Table serializes calls to engine,
+// but rows returned by Snapshot may be read concurrently with changes.
+type Engine interface {
+	Kind() EngineKind
+	// Rows count
+	Len() int
+	// Call fn for every row in order, stops on the first error
+	Scan(fn func(id int, row ColumnSet) error) error
+	Insert(rows []ColumnSet) error
+	UpdateByID(id int, row ColumnSet) error
+	// Delete rows by ids sorted in ascending order
+	DeleteByID(ids []int) error
+	// Rows at the moment of call, that are not affected by further changes
+	Snapshot() (Rows, error)
+	// Reclaim storage of replaced and deleted rows
+	Vacuum() error
+	// Release engine resources, engine is not usable after it
+	Close() error
+}
+
+// Read only rows. Rows may hold storage of engine, so they are released,
+// once they are not read anymore.
+type Rows interface {
+	Len() int
+	Scan(fn func(row ColumnSet) error) error
+	// Release storage of rows, rows must not be read after it
+	Release()
+}
+
+// Error of table or engine, that is used after it was closed
+var ErrClosed = errors.New("table is closed")
+
+// Rows, that stop scan with error of ctx, once ctx is done
+func RowsWithContext(ctx context.Context, rows Rows) Rows {
+	return contextRows{ctx, rows}
+}
+
+type contextRows struct {
+	ctx context.Context
+	Rows
+}
+
+func (r contextRows) Scan(fn func(row ColumnSet) error) error {
+	return r.Rows.Scan(func(row ColumnSet) error {
+		if err := r.ctx.Err(); err != nil {
+			return err
+		}
+		return fn(row)
+	})
+}
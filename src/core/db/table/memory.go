@@ -0,0 +1,91 @@
+package table
+
+import "golang.org/x/exp/slices"
+
+// Engine, that keeps rows in slice
+type memoryEngine struct {
+	data []ColumnSet
+	// shared is set when data is referenced by snapshot,
+	// data is copied on write then
+	shared bool
+}
+
+func NewMemoryEngine() Engine {
+	return &memoryEngine{}
+}
+
+func (e *memoryEngine) Kind() EngineKind {
+	return MemoryEngine
+}
+
+func (e *memoryEngine) Len() int {
+	return len(e.data)
+}
+
+func (e *memoryEngine) Scan(fn func(id int, row ColumnSet) error) error {
+	for i, row := range e.data {
+		if err := fn(i, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshot has no spare capacity, so appending never touches it
+func (e *memoryEngine) Insert(rows []ColumnSet) error {
+	e.data = append(e.data, rows...)
+	return nil
+}
+
+// Rows are replaced, not changed in place, because they may be in snapshot
+func (e *memoryEngine) UpdateByID(id int, row ColumnSet) error {
+	if e.shared {
+		e.data = slices.Clone(e.data)
+		e.shared = false
+	}
+	e.data[id] = row
+	return nil
+}
+
+func (e *memoryEngine) DeleteByID(ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	e.data = removeIndexes(e.data, ids)
+	e.shared = false
+	return nil
+}
+
+// Snapshot shares data with engine instead of copying it
+func (e *memoryEngine) Snapshot() (Rows, error) {
+	e.shared = true
+	return sliceRows(e.data[:len(e.data):len(e.data)]), nil
+}
+
+// Replaced rows are released by garbage collector
+func (e *memoryEngine) Vacuum() error {
+	return nil
+}
+
+// Data is released by garbage collector
+func (e *memoryEngine) Close() error {
+	return nil
+}
+
+type sliceRows []ColumnSet
+
+func (r sliceRows) Len() int {
+	return len(r)
+}
+
+func (r sliceRows) Scan(fn func(row ColumnSet) error) error {
+	for _, row := range r {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rows are released by garbage collector
+func (r sliceRows) Release() {}
@@ -1,12 +1,13 @@
 package table
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"hash/fnv"
-	"log"
-	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/elliotchance/pie/v2"
 	dbtypes "github.com/ssyrota/frog-db/src/core/db/dbtypes"
@@ -18,43 +19,271 @@ import (
 
 type ColumnSet map[string]any
 
-// Validate schema and create new table
+// System column with version of row. Version starts from 1 and is
+// incremented on every update of row. Column is not a part of schema,
+// it is selected only when requested explicitly.
+const VersionColumn = "$version"
+
+// Names, that columns of schema can't have
+var reservedColumns = []string{VersionColumn, AnyOf, AllOf, Not}
+
+// Version of row, 0 if row has none
+func RowVersion(row ColumnSet) int64 {
+	version, _ := row[VersionColumn].(int64)
+	return version
+}
+
+// Validate schema and create new table stored in memory
 func NewTable(sch schema.T) (*T, error) {
+	return NewTableWithEngine(sch, NewMemoryEngine())
+}
+
+// Validate schema and create new table stored by engine,
+// engine is closed if schema is invalid
+func NewTableWithEngine(sch schema.T, engine Engine) (*T, error) {
+	if err := ValidateSchema(sch); err != nil {
+		engine.Close()
+		return nil, err
+	}
+	return &T{schema: sch, engine: engine}, nil
+}
+
+// Check that columns of schema have known types and are not reserved
+func ValidateSchema(sch schema.T) error {
 	for column, t := range sch {
+		if slices.Contains(reservedColumns, column) {
+			return errs.NewErrReservedColumn(column)
+		}
 		if !dbtypes.IsAvailableName(string(t)) {
-			return nil, errs.NewErrInvalidTypeProvided(column, string(t))
+			return errs.NewErrInvalidTypeProvided(column, string(t))
 		}
 	}
-	return &T{schema: sch}, nil
+	return nil
 }
 
 type T struct {
 	mu     sync.RWMutex
 	schema schema.T
-	data   []ColumnSet
+	engine Engine
+	// version is incremented on every change of data
+	version uint64
+	// Snapshot of rows, that selects read without locking table. It is
+	// taken by the first select after change and is shared by selects
+	// until the next change. Replaced snapshot is released, when the last
+	// select reading it is done.
+	view   atomic.Pointer[tableView]
+	closed bool
+}
+
+type tableView struct {
+	rows Rows
+	// Selects reading view and one more reference while view is current
+	refs atomic.Int64
+}
+
+// Add reference to view, false if view is released already
+func (v *tableView) retain() bool {
+	for {
+		refs := v.refs.Load()
+		if refs == 0 {
+			return false
+		}
+		if v.refs.CompareAndSwap(refs, refs+1) {
+			return true
+		}
+	}
+}
+
+func (v *tableView) release() {
+	if v.refs.Add(-1) == 0 {
+		v.rows.Release()
+	}
+}
+
+// Mark data changed. Caller must hold mu.
+func (t *T) changed() {
+	t.version++
+	t.dropView()
+}
+
+// Release current view, it is freed once selects reading it are done.
+// Caller must hold mu.
+func (t *T) dropView() {
+	if view := t.view.Swap(nil); view != nil {
+		view.release()
+	}
+}
+
+// The latest snapshot, caller releases it after reading
+func (t *T) readView() (*tableView, error) {
+	if view := t.view.Load(); view != nil && view.retain() {
+		return view, nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.currentView()
+}
+
+// The latest snapshot and version of table, that it reflects. Changes are
+// prepared from it without holding mu, they are stale, if table changes
+// meanwhile. Caller releases snapshot after reading.
+func (t *T) versionedView() (*tableView, uint64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	view, err := t.currentView()
+	if err != nil {
+		return nil, 0, err
+	}
+	return view, t.version, nil
+}
+
+// Retained current view, it's taken if there is none. Caller must hold mu.
+func (t *T) currentView() (*tableView, error) {
+	if t.closed {
+		return nil, ErrClosed
+	}
+	if view := t.view.Load(); view != nil && view.retain() {
+		return view, nil
+	}
+	rows, err := t.engine.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	view := &tableView{rows: rows}
+	view.refs.Store(2)
+	t.view.Store(view)
+	return view, nil
+}
+
+// Reclaim storage of replaced and deleted rows
+func (t *T) Vacuum() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil
+	}
+	if err := t.engine.Vacuum(); err != nil {
+		return err
+	}
+	// Snapshot may refer to reclaimed storage
+	t.dropView()
+	return nil
 }
 
 // Dump table.
+// Dump read from file has Data, dump of table has Rows instead.
 type Dump struct {
 	Schema schema.T    `json:"schema"`
 	Data   []ColumnSet `json:"data"`
 	Name   string      `json:"name"`
+	Engine EngineKind  `json:"engine,omitempty"`
+	// Version of table data in dump
+	Version uint64 `json:"-"`
+	Rows    Rows   `json:"-"`
+}
+
+// Rows count of dump
+func (d *Dump) Len() int {
+	if d.Rows != nil {
+		return d.Rows.Len()
+	}
+	return len(d.Data)
+}
+
+// Call fn for every row of dump
+func (d *Dump) Scan(fn func(row ColumnSet) error) error {
+	if d.Rows != nil {
+		return d.Rows.Scan(fn)
+	}
+	return sliceRows(d.Data).Scan(fn)
+}
+
+// Release rows of dump of table, dump must not be scanned after it
+func (d *Dump) Release() {
+	if d.Rows != nil {
+		d.Rows.Release()
+	}
 }
 
+// Dump refers to snapshot of engine instead of copying rows, so dump is
+// cheap and stays unchanged while table changes. Dumped rows are read only,
+// dump is released after reading. Closed table has no dump.
 func (t *T) Dump(tableName string) (*Dump, error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	if t.closed {
+		return nil, ErrClosed
+	}
+	rows, err := t.engine.Snapshot()
+	if err != nil {
+		return nil, err
+	}
 	var dump Dump
-	dump.Data = t.data
+	dump.Rows = rows
 	dump.Schema = t.schema
 	dump.Name = tableName
+	dump.Engine = t.engine.Kind()
+	dump.Version = t.version
 	return &dump, nil
 }
 
-func (t *T) LoadDump(data *[]ColumnSet) error {
-	inserted, err := t.InsertRows(data)
-	log.Printf("%v", len(*data) == int(inserted))
-	return err
+// Copy rows of table to empty table with the same schema, tables are changed
+// independently after it. Table shares rows with its copy of the same
+// engine until one of them changes, disk table shares its file too.
+func (t *T) CopyTo(to *T) error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return ErrClosed
+	}
+	rows, err := t.engine.Snapshot()
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	defer rows.Release()
+	to.mu.Lock()
+	defer to.mu.Unlock()
+	to.changed()
+	if m, ok := to.engine.(*memoryEngine); ok && m.Len() == 0 {
+		if data, ok := rows.(sliceRows); ok {
+			m.data = data
+			m.shared = true
+			return nil
+		}
+	}
+	if d, ok := to.engine.(*diskEngine); ok && d.Len() == 0 {
+		if snapshot, ok := rows.(*diskRows); ok {
+			return d.share(snapshot)
+		}
+	}
+	batch := make([]ColumnSet, 0, copyBatchSize)
+	err = rows.Scan(func(row ColumnSet) error {
+		batch = append(batch, row)
+		if len(batch) < copyBatchSize {
+			return nil
+		}
+		err := to.engine.Insert(batch)
+		batch = batch[:0]
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(batch) != 0 {
+		return to.engine.Insert(batch)
+	}
+	return nil
+}
+
+// Rows inserted at once by CopyTo
+const copyBatchSize = 1000
+
+// Mutation counter of table, changes whenever table data changes
+func (t *T) Version() uint64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.version
 }
 
 // Introspect schema
@@ -64,124 +293,316 @@ func (t *T) Schema() schema.T {
 	return t.schema
 }
 
-// Insert rows to table
+// Storage engine kind of table
+func (t *T) EngineKind() EngineKind {
+	return t.engine.Kind()
+}
+
+// Release storage of table. Storage, that is read by selects and dumps,
+// is released once they are done.
+func (t *T) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	t.dropView()
+	return t.engine.Close()
+}
+
+// Insert rows to table, inserted rows have version 1
 func (t *T) InsertRows(rows *[]ColumnSet) (uint, error) {
+	change, err := t.PrepareInsert(rows)
+	if err != nil {
+		return 0, err
+	}
+	return change.Apply()
+}
+
+// Insert rows of dump to table, keeping their versions
+func (t *T) LoadRows(rows *[]ColumnSet) (uint, error) {
+	change, err := t.prepareInsert(rows, true)
+	if err != nil {
+		return 0, err
+	}
+	return change.Apply()
+}
+
+// Validate rows to insert, see InsertRows
+func (t *T) PrepareInsert(rows *[]ColumnSet) (*Change, error) {
+	return t.prepareInsert(rows, false)
+}
+
+func (t *T) prepareInsert(rows *[]ColumnSet, keepVersions bool) (*Change, error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	rowsToInsert := make([]ColumnSet, len(*rows))
-	requiredColumns := MapKeys(t.schema)
 	for i, row := range *rows {
-		rowColumns := MapKeys(row)
-		// Check required columns
-		omitted := pie.Filter(requiredColumns, func(a string) bool {
-			return !slices.Contains(rowColumns, a)
-		})
-		if len(omitted) != 0 {
-			return 0, errs.NewErrColumnsRequired(omitted)
-		}
-		// Check extra columns
-		extra := pie.Filter(rowColumns, func(a string) bool {
-			return !slices.Contains(requiredColumns, a)
-		})
-		if len(extra) != 0 {
-			return 0, errs.NewErrColumnsNotFound(extra)
-		}
-		// Validate types
-		rowToInsert := make(ColumnSet)
-		for k, v := range row {
-			typedVal, err := dbtypes.NewDataVal(t.schema[k], v)
+		version := int64(1)
+		if rawVersion, ok := row[VersionColumn]; ok && keepVersions {
+			typedVersion, err := dbtypes.NewDataVal(dbtypes.Integer, rawVersion)
 			if err != nil {
-				return 0, err
+				return nil, err
 			}
-			rowToInsert[k] = typedVal
+			version = typedVersion.(int64)
+			row = WithoutVersion(row)
+		}
+		rowToInsert, err := ValidateRow(t.schema, row)
+		if err != nil {
+			return nil, err
 		}
+		rowToInsert[VersionColumn] = version
 		rowsToInsert[i] = rowToInsert
 	}
-	t.data = append(t.data, rowsToInsert...)
-	return uint(len(rowsToInsert)), nil
+	return t.newChange(t.version, uint(len(rowsToInsert)), func() error {
+		if err := t.engine.Insert(rowsToInsert); err != nil {
+			return err
+		}
+		t.changed()
+		return nil
+	}), nil
 }
 
-// Update rows in table
-func (t *T) UpdateRows(rawCondition ColumnSet, newRawData ColumnSet) (uint, error) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	newData, err := t.setFromRaw(newRawData)
-	if err != nil {
+// Change of table, that is validated, but not applied yet. Nothing
+// is applied, if table changes after change was prepared.
+type Change struct {
+	t       *T
+	version uint64
+	// Rows count, that change affects
+	count uint
+	// Called holding mu
+	apply func() error
+}
+
+// Error of change, that is applied after table changed
+var ErrStaleChange = errors.New("table changed after change was prepared")
+
+// Create change of rows count, that is prepared at version of table
+func (t *T) newChange(version uint64, count uint, apply func() error) *Change {
+	return &Change{t: t, version: version, count: count, apply: apply}
+}
+
+// Apply change and get count of affected rows
+func (c *Change) Apply() (uint, error) {
+	c.t.mu.Lock()
+	defer c.t.mu.Unlock()
+	if c.t.version != c.version {
+		return 0, ErrStaleChange
+	}
+	if c.count == 0 {
+		return 0, nil
+	}
+	if err := c.apply(); err != nil {
 		return 0, err
 	}
-	ids, err := t.filter(rawCondition)
+	return c.count, nil
+}
+
+// Check that row has every column of schema and convert its values to
+// column types
+func ValidateRow(sch schema.T, row ColumnSet) (ColumnSet, error) {
+	requiredColumns := MapKeys(sch)
+	rowColumns := MapKeys(row)
+	// Check required columns
+	omitted := pie.Filter(requiredColumns, func(a string) bool {
+		return !slices.Contains(rowColumns, a)
+	})
+	if len(omitted) != 0 {
+		return nil, errs.NewErrColumnsRequired(omitted)
+	}
+	// Check extra columns
+	extra := pie.Filter(rowColumns, func(a string) bool {
+		return !slices.Contains(requiredColumns, a)
+	})
+	if len(extra) != 0 {
+		return nil, errs.NewErrColumnsNotFound(extra)
+	}
+	// Validate types
+	typedRow := make(ColumnSet)
+	for k, v := range row {
+		typedVal, err := dbtypes.NewDataVal(sch[k], v)
+		if err != nil {
+			return nil, err
+		}
+		typedRow[k] = typedVal
+	}
+	return typedRow, nil
+}
+
+// Update rows in table. If expectedVersion is set, every row, that matches
+// condition, must have it, otherwise nothing is updated. Nothing is updated
+// either, if ctx is done while rows are scanned.
+func (t *T) UpdateRows(ctx context.Context, rawCondition ColumnSet, newRawData ColumnSet, expectedVersion *int64) (uint, error) {
+	change, err := t.PrepareUpdate(ctx, rawCondition, newRawData, expectedVersion)
 	if err != nil {
 		return 0, err
 	}
-	for _, v := range *ids {
-		rawToUpdate := t.data[v]
+	return change.Apply()
+}
+
+// Find and check rows to update, see UpdateRows. Rows are scanned from
+// snapshot, so selects are not blocked meanwhile.
+func (t *T) PrepareUpdate(ctx context.Context, rawCondition ColumnSet, newRawData ColumnSet, expectedVersion *int64) (*Change, error) {
+	newData, err := t.setFromRaw(newRawData)
+	if err != nil {
+		return nil, err
+	}
+	view, version, err := t.versionedView()
+	if err != nil {
+		return nil, err
+	}
+	defer view.release()
+	ids, rows, err := t.filter(ctx, view.rows, rawCondition)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersions(rows, expectedVersion); err != nil {
+		return nil, err
+	}
+	// Rows are replaced, not changed in place, because they may be dumped
+	updated := make([]ColumnSet, len(rows))
+	for i, row := range rows {
+		rawToUpdate := make(ColumnSet, len(row))
+		for column, value := range row {
+			rawToUpdate[column] = value
+		}
 		for column, updatedValue := range newData {
 			rawToUpdate[column] = updatedValue
 		}
+		rawToUpdate[VersionColumn] = RowVersion(row) + 1
+		updated[i] = rawToUpdate
 	}
-	return uint(len(*ids)), nil
+	return t.newChange(version, uint(len(ids)), func() error {
+		for i, id := range ids {
+			if err := t.engine.UpdateByID(id, updated[i]); err != nil {
+				// Some rows may be updated already
+				t.changed()
+				return err
+			}
+		}
+		t.changed()
+		return nil
+	}), nil
 }
 
-// Update rows from table
-func (t *T) DeleteRows(rawCondition ColumnSet) (uint, error) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	ids, err := t.filter(rawCondition)
+// Delete rows from table. If expectedVersion is set, every row, that matches
+// condition, must have it, otherwise nothing is deleted. Nothing is deleted
+// either, if ctx is done while rows are scanned.
+func (t *T) DeleteRows(ctx context.Context, rawCondition ColumnSet, expectedVersion *int64) (uint, error) {
+	change, err := t.PrepareDelete(ctx, rawCondition, expectedVersion)
 	if err != nil {
 		return 0, err
 	}
-	t.data = removeIndexes(t.data, *ids)
-	return uint(len(*ids)), nil
+	return change.Apply()
 }
 
-// Update rows from table
-func (t *T) DeleteDuplicates() (uint, error) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+// Find and check rows to delete, see DeleteRows. Rows are scanned from
+// snapshot, so selects are not blocked meanwhile.
+func (t *T) PrepareDelete(ctx context.Context, rawCondition ColumnSet, expectedVersion *int64) (*Change, error) {
+	view, version, err := t.versionedView()
+	if err != nil {
+		return nil, err
+	}
+	defer view.release()
+	ids, rows, err := t.filter(ctx, view.rows, rawCondition)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersions(rows, expectedVersion); err != nil {
+		return nil, err
+	}
+	return t.deleteChange(version, ids), nil
+}
+
+// Change, that deletes rows by ids at version of table
+func (t *T) deleteChange(version uint64, ids []int) *Change {
+	return t.newChange(version, uint(len(ids)), func() error {
+		if err := t.engine.DeleteByID(ids); err != nil {
+			return err
+		}
+		t.changed()
+		return nil
+	})
+}
+
+// Delete duplicate rows from table
+func (t *T) DeleteDuplicates(ctx context.Context) (uint, error) {
+	change, err := t.PrepareDeleteDuplicates(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return change.Apply()
+}
+
+// Find duplicate rows to delete, see DeleteDuplicates. Rows are scanned
+// from snapshot, so selects are not blocked meanwhile.
+func (t *T) PrepareDeleteDuplicates(ctx context.Context) (*Change, error) {
+	view, version, err := t.versionedView()
+	if err != nil {
+		return nil, err
+	}
+	defer view.release()
 	columnNames := make([]string, 0, len(t.schema))
 	for k := range t.schema {
 		columnNames = append(columnNames, k)
 	}
-	uniqueSet := Set[ColumnSet]{}
-	deleted := uint(0)
-	for _, row := range t.data {
+	uniqueSet := Set[struct{}]{}
+	duplicates := []int{}
+	// Ids are positions of rows in snapshot
+	id := -1
+	err = RowsWithContext(ctx, view.rows).Scan(func(row ColumnSet) error {
+		id++
 		orderedColumnsStr := strings.Builder{}
 		for _, columnName := range columnNames {
 			_, err := orderedColumnsStr.WriteString(fmt.Sprint(row[columnName]))
 			if err != nil {
-				return 0, err
+				return err
 			}
 		}
 		rowHash := hash(orderedColumnsStr.String())
 		if _, ok := uniqueSet[fmt.Sprint(rowHash)]; ok {
-			deleted++
+			duplicates = append(duplicates, id)
 		} else {
-			uniqueSet[fmt.Sprint(rowHash)] = row
+			uniqueSet[fmt.Sprint(rowHash)] = struct{}{}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	t.data = []ColumnSet{}
-	for _, v := range uniqueSet {
-		t.data = append(t.data, v)
-	}
-	return deleted, nil
+	return t.deleteChange(version, duplicates), nil
 }
 
 // Select data from table,
-// empty columns list and empty conditions considered as "select all"
-func (t *T) SelectRows(columns *[]string, conditions ColumnSet) (*[]ColumnSet, error) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	filteredIDs, err := t.filter(conditions)
+// empty columns list and empty conditions considered as "select all".
+// Rows are read from snapshot, so select does not block writers.
+// Closed table can't be selected from.
+// Scan stops with error of ctx, once ctx is done.
+func (t *T) SelectRows(ctx context.Context, columns *[]string, conditions ColumnSet) (*[]ColumnSet, error) {
+	condition, err := ParseCondition(t.schema, conditions)
+	if err != nil {
+		return nil, err
+	}
+	view, err := t.readView()
 	if err != nil {
 		return nil, err
 	}
-	res := make([]ColumnSet, len(*filteredIDs))
-	for i, id := range *filteredIDs {
-		row, err := t.removeExtraFields(t.data[id], columns)
+	defer view.release()
+	res := []ColumnSet{}
+	err = RowsWithContext(ctx, view.rows).Scan(func(row ColumnSet) error {
+		if ok, err := condition.Match(row); err != nil || !ok {
+			return err
+		}
+		selected, err := t.removeExtraFields(row, columns)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		res[i] = row
+		res = append(res, selected)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return &res, nil
 }
@@ -190,7 +611,9 @@ func (t *T) removeExtraFields(row ColumnSet, requiredColumns *[]string) (ColumnS
 	if err != nil {
 		return nil, err
 	}
-	if len(*requiredColumns) != 0 {
+	if len(*requiredColumns) == 0 {
+		delete(copied, VersionColumn)
+	} else {
 		for columnName := range copied {
 			if !slices.Contains(*requiredColumns, columnName) {
 				delete(copied, columnName)
@@ -200,24 +623,55 @@ func (t *T) removeExtraFields(row ColumnSet, requiredColumns *[]string) (ColumnS
 	return copied, nil
 }
 
-// Get indexes from data, that matches conditions.
-// when condition is empty filter returns all data ids from table
-func (t *T) filter(rawCondition ColumnSet) (*[]int, error) {
-	condition, err := t.setFromRaw(rawCondition)
+// Check that every row has expected version, if it is set
+func checkVersions(rows []ColumnSet, expectedVersion *int64) error {
+	if expectedVersion == nil {
+		return nil
+	}
+	for _, row := range rows {
+		if version := RowVersion(row); version != *expectedVersion {
+			return errs.NewErrVersionConflict(*expectedVersion, version)
+		}
+	}
+	return nil
+}
+
+// Copy of row without version column
+func WithoutVersion(row ColumnSet) ColumnSet {
+	copied := make(ColumnSet, len(row))
+	for k, v := range row {
+		if k != VersionColumn {
+			copied[k] = v
+		}
+	}
+	return copied
+}
+
+// Get ids and rows of snapshot, that match conditions. Ids are positions
+// of rows in snapshot, they are ids of engine at version of snapshot.
+// when condition is empty filter returns all rows from table.
+// Scan stops with error of ctx, once ctx is done.
+func (t *T) filter(ctx context.Context, snapshot Rows, rawCondition ColumnSet) ([]int, []ColumnSet, error) {
+	condition, err := ParseCondition(t.schema, rawCondition)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	res := []int{}
-rows:
-	for i, row := range t.data {
-		for k, v := range condition {
-			if !reflect.DeepEqual(row[k], v) {
-				continue rows
-			}
+	ids := []int{}
+	rows := []ColumnSet{}
+	id := -1
+	err = RowsWithContext(ctx, snapshot).Scan(func(row ColumnSet) error {
+		id++
+		ok, err := condition.Match(row)
+		if ok {
+			ids = append(ids, id)
+			rows = append(rows, row)
 		}
-		res = append(res, i)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
 	}
-	return &res, nil
+	return ids, rows, nil
 }
 
 // Convert raw map to typed ColumnSet
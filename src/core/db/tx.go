@@ -0,0 +1,249 @@
+package db
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/ssyrota/frog-db/src/core/db/deepcopy"
+	"github.com/ssyrota/frog-db/src/core/db/table"
+	errs "github.com/ssyrota/frog-db/src/core/err"
+)
+
+// Transaction sees its own changes, others see them only after commit.
+// Table is copied when transaction changes it first time, copy is cheap,
+// because it shares rows with table until one of them changes.
+// Commit fails with ErrTxConflict, if any table read or changed by
+// transaction was changed by someone else meanwhile.
+type Tx struct {
+	db *Database
+	mu sync.Mutex
+	// Tables seen by transaction, nil for dropped ones
+	tables map[string]*table.T
+	// Tables of db at the moment transaction touched them first
+	base map[string]txBase
+	// Commands, that changed tables of transaction
+	commands []any
+	done     bool
+}
+
+type txBase struct {
+	// nil if there was no table
+	table   *table.T
+	version uint64
+}
+
+// Commands of committed transaction, they are logged to wal as a whole
+type CommandTx struct {
+	Commands []any
+}
+
+func init() {
+	gob.Register(&CommandTx{})
+}
+
+// Begin implementation.
+func (db *Database) Begin() *Tx {
+	return &Tx{db: db, tables: make(map[string]*table.T), base: make(map[string]txBase)}
+}
+
+// Execute command within transaction
+func (tx *Tx) Execute(command any) (*[]table.ColumnSet, error) {
+	return tx.ExecuteContext(context.Background(), command)
+}
+
+// Execute command within transaction, scans stop once ctx is done.
+// Transaction is not changed by cancelled command.
+func (tx *Tx) ExecuteContext(ctx context.Context, command any) (*[]table.ColumnSet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextErr(err)
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return nil, errs.NewErrTxDone()
+	}
+	// Command is logged only on commit, so the copy is kept, that can't be
+	// changed by caller meanwhile
+	mutating := isMutating(command)
+	if mutating {
+		copied, err := deepcopy.Value(command)
+		if err != nil {
+			return nil, err
+		}
+		command = copied
+	}
+	res, err := tx.db.executeIn(ctx, tx, command)
+	if err != nil {
+		return nil, err
+	}
+	if mutating {
+		tx.commands = append(tx.commands, command)
+	}
+	return res, nil
+}
+
+// Apply changes of transaction to db atomically
+func (tx *Tx) Commit() error {
+	return tx.commit(true)
+}
+
+// Discard changes of transaction
+func (tx *Tx) Rollback() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return errs.NewErrTxDone()
+	}
+	tx.done = true
+	tx.closeCopies()
+	return nil
+}
+
+// Commit changes, logging them to wal if logged is set
+func (tx *Tx) commit(logged bool) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return errs.NewErrTxDone()
+	}
+	tx.done = true
+	db := tx.db
+	db.writeMu.Lock()
+	defer db.writeMu.Unlock()
+	for name, b := range tx.base {
+		current := db.tables[name]
+		if current != b.table || (current != nil && current.Version() != b.version) {
+			tx.closeCopies()
+			return errs.NewErrTxConflict(name)
+		}
+	}
+	if len(tx.commands) == 0 {
+		tx.closeCopies()
+		return nil
+	}
+	if logged {
+		if err := db.wal.Append(&CommandTx{tx.commands}); err != nil {
+			tx.closeCopies()
+			return errs.NewErrDbIO(err)
+		}
+	}
+	replaced := []*table.T{}
+	db.tablesMu.Lock()
+	for name, t := range tx.tables {
+		if t == tx.base[name].table {
+			continue
+		}
+		if old := db.tables[name]; old != nil {
+			replaced = append(replaced, old)
+		}
+		if t == nil {
+			delete(db.tables, name)
+		} else {
+			db.tables[name] = t
+		}
+	}
+	db.tablesMu.Unlock()
+	// Selects and dumps keep reading snapshots of replaced tables,
+	// the later ones read tables of commit
+	for _, t := range replaced {
+		if err := t.Close(); err != nil {
+			log.Printf("commit: close replaced table: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// Release tables created by transaction
+func (tx *Tx) closeCopies() {
+	for name, t := range tx.tables {
+		if t != nil && t != tx.base[name].table {
+			if err := t.Close(); err != nil {
+				log.Printf("rollback: close table %s: %s", name, err.Error())
+			}
+		}
+	}
+}
+
+// Apply commands of transaction from wal
+func (db *Database) replayTx(command *CommandTx) error {
+	tx := db.Begin()
+	for _, c := range command.Commands {
+		if _, err := tx.Execute(c); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.commit(false)
+}
+
+// Remember table of db, that is touched by transaction first time
+func (tx *Tx) touch(name string) {
+	if _, ok := tx.base[name]; ok {
+		return
+	}
+	t, err := tx.db.table(name)
+	if err != nil {
+		tx.base[name] = txBase{}
+		return
+	}
+	tx.base[name] = txBase{t, t.Version()}
+	tx.tables[name] = t
+}
+
+func (tx *Tx) table(name string) (*table.T, error) {
+	tx.touch(name)
+	t := tx.tables[name]
+	if t == nil {
+		return nil, errs.NewErrTableNotFound(name)
+	}
+	return t, nil
+}
+
+// Table of db is copied on first change
+func (tx *Tx) tableForUpdate(name string) (*table.T, error) {
+	t, err := tx.table(name)
+	if err != nil || t != tx.base[name].table {
+		return t, err
+	}
+	copied, err := tx.db.newTable(name, t.Schema(), t.EngineKind())
+	if err != nil {
+		return nil, err
+	}
+	if err := t.CopyTo(copied); err != nil {
+		copied.Close()
+		if errors.Is(err, table.ErrClosed) {
+			return nil, errs.NewErrTxConflict(name)
+		}
+		return nil, errs.NewErrDbIO(err)
+	}
+	tx.tables[name] = copied
+	return copied, nil
+}
+
+// Tables of transaction are closed only when it is done, so closed table
+// is the table of db, that was dropped or replaced by other transaction
+func (tx *Tx) reopenTable(name string, closed *table.T) (*table.T, error) {
+	return nil, errs.NewErrTxConflict(name)
+}
+
+func (tx *Tx) addTable(name string, t *table.T) {
+	tx.touch(name)
+	tx.tables[name] = t
+}
+
+func (tx *Tx) removeTable(name string) error {
+	t, err := tx.table(name)
+	if err != nil {
+		return err
+	}
+	if t != tx.base[name].table {
+		if err := t.Close(); err != nil {
+			log.Printf("drop table %s: %s", name, err.Error())
+		}
+	}
+	tx.tables[name] = nil
+	return nil
+}
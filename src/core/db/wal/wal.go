@@ -0,0 +1,212 @@
+// Package wal provides an append-only write-ahead log of db commands.
+//
+// Every record is framed as [length uint32][crc32 uint32][gob payload] and is
+// fsync'd before Append returns. The payload is encoded with a fresh gob
+// encoder, so records are self-contained and the log can be replayed after a
+// restart. Concrete entry types must be registered with gob.Register.
+package wal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	errs "github.com/ssyrota/frog-db/src/core/err"
+)
+
+const headerSize = 8
+
+type record struct {
+	Entry any
+}
+
+type Log struct {
+	mu   sync.Mutex
+	file *os.File
+	path string
+	size int64
+}
+
+// Open log file, create it if not exists
+func Open(path string) (*Log, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &Log{file: file, path: path, size: info.Size()}, nil
+}
+
+// Append entry to the log and flush it to stable storage
+func (l *Log) Append(entry any) error {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(&record{entry}); err != nil {
+		return fmt.Errorf("encode wal record: %w", err)
+	}
+	frame := make([]byte, headerSize+payload.Len())
+	binary.LittleEndian.PutUint32(frame[0:4], uint32(payload.Len()))
+	binary.LittleEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(payload.Bytes()))
+	copy(frame[headerSize:], payload.Bytes())
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(frame); err != nil {
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	l.size += int64(len(frame))
+	return nil
+}
+
+// Replay calls apply for every entry in the log in order of appending.
+// A torn record at the end of the log (crash in the middle of Append), that
+// runs past the end of file, is cut off, because it was never acknowledged.
+// Record with checksum mismatch is never cut, because records after it, or
+// the record itself, may be acknowledged, so replay fails with
+// ErrCorruptedWal instead.
+func (l *Log) Replay(apply func(entry any) error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	var offset int64
+	header := make([]byte, headerSize)
+	for {
+		if _, err := io.ReadFull(l.file, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return l.cut(offset)
+			}
+			return err
+		}
+		// Length is checked before allocation, so corrupted length never
+		// allocates more than the log holds
+		length := int64(binary.LittleEndian.Uint32(header[0:4]))
+		if length > l.size-offset-headerSize {
+			return l.cut(offset)
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(l.file, payload); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return l.cut(offset)
+			}
+			return err
+		}
+		if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:8]) {
+			return errs.NewErrCorruptedWal(l.path, offset, "checksum mismatch")
+		}
+		var rec record
+		if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&rec); err != nil {
+			return fmt.Errorf("decode wal record at offset %d: %w", offset, err)
+		}
+		if err := apply(rec.Entry); err != nil {
+			return err
+		}
+		offset += int64(headerSize + len(payload))
+	}
+}
+
+// Drop torn tail of the log
+func (l *Log) cut(offset int64) error {
+	log.Printf("wal: dropping torn record at offset %d of %s", offset, l.path)
+	if err := l.file.Truncate(offset); err != nil {
+		return err
+	}
+	l.size = offset
+	return l.file.Sync()
+}
+
+// Discard records before offset, records appended after it are kept.
+// Offset is a size of the log at some moment, e.g. when dump was taken.
+func (l *Log) Discard(offset int64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if offset == 0 {
+		return nil
+	}
+	if offset == l.size {
+		if err := l.file.Truncate(0); err != nil {
+			return err
+		}
+		l.size = 0
+		return l.file.Sync()
+	}
+	tail := make([]byte, l.size-offset)
+	if _, err := l.file.ReadAt(tail, offset); err != nil {
+		return err
+	}
+	// Write tail aside and rename it over the log, so crash never loses records
+	tmpPath := l.path + ".tmp"
+	if err := writeSynced(tmpPath, tail); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, l.path); err != nil {
+		return err
+	}
+	if err := syncDir(filepath.Dir(l.path)); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(l.path, os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	l.file.Close()
+	l.file = file
+	l.size = int64(len(tail))
+	return nil
+}
+
+func writeSynced(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Size of the log in bytes
+func (l *Log) Size() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.size
+}
+
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
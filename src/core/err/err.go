@@ -87,3 +87,87 @@ type ErrDbIO struct {
 func NewErrDbIO(err error) *ErrDbIO {
 	return &ErrDbIO{fmt.Errorf("db io error: %s", err.Error())}
 }
+
+type ErrCorruptedDump struct {
+	error
+}
+
+func NewErrCorruptedDump(path string, err error) *ErrCorruptedDump {
+	return &ErrCorruptedDump{fmt.Errorf("dump %s is corrupted: %s", path, err.Error())}
+}
+
+type ErrDumpChecksum struct {
+	error
+}
+
+func NewErrDumpChecksum(path string, section string) *ErrDumpChecksum {
+	return &ErrDumpChecksum{fmt.Errorf("dump %s checksum mismatch: %s", path, section)}
+}
+
+type ErrCorruptedWal struct {
+	error
+}
+
+func NewErrCorruptedWal(path string, offset int64, reason string) *ErrCorruptedWal {
+	return &ErrCorruptedWal{fmt.Errorf("wal %s is corrupted at offset %d: %s", path, offset, reason)}
+}
+
+type ErrTxConflict struct {
+	error
+}
+
+func NewErrTxConflict(tableName string) *ErrTxConflict {
+	return &ErrTxConflict{fmt.Errorf("transaction conflict: table %s was changed by another transaction", tableName)}
+}
+
+type ErrTxDone struct {
+	error
+}
+
+func NewErrTxDone() *ErrTxDone {
+	return &ErrTxDone{fmt.Errorf("transaction is already committed or rolled back")}
+}
+
+type ErrTxNotFound struct {
+	error
+}
+
+func NewErrTxNotFound(id string) *ErrTxNotFound {
+	return &ErrTxNotFound{fmt.Errorf("transaction %s not found", id)}
+}
+
+type ErrVersionConflict struct {
+	error
+}
+
+func NewErrVersionConflict(expected, actual int64) *ErrVersionConflict {
+	return &ErrVersionConflict{fmt.Errorf("row version conflict: expected version %d, row has version %d", expected, actual)}
+}
+
+type ErrReservedColumn struct {
+	error
+}
+
+func NewErrReservedColumn(columnName string) *ErrReservedColumn {
+	return &ErrReservedColumn{fmt.Errorf("column name %s is reserved", columnName)}
+}
+
+type ErrTimeout struct {
+	error
+}
+
+func NewErrTimeout() *ErrTimeout {
+	return &ErrTimeout{fmt.Errorf("operation timed out")}
+}
+
+type ErrInvalidCondition struct {
+	error
+}
+
+func NewErrInvalidCondition(columnName string, reason string) *ErrInvalidCondition {
+	return &ErrInvalidCondition{fmt.Errorf("invalid condition on column %s: %s", columnName, reason)}
+}
+
+func NewErrInvalidConditionKey(key string, reason string) *ErrInvalidCondition {
+	return &ErrInvalidCondition{fmt.Errorf("invalid condition %s: %s", key, reason)}
+}
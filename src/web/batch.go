@@ -0,0 +1,101 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/ssyrota/frog-db/src/core/db"
+	"github.com/ssyrota/frog-db/src/core/db/dbtypes"
+	"github.com/ssyrota/frog-db/src/core/db/schema"
+	"github.com/ssyrota/frog-db/src/core/db/table"
+	"github.com/ssyrota/frog-db/src/web/server"
+)
+
+// ExecuteBatch implementation.
+func (h *handler) ExecuteBatch(ctx context.Context, request server.ExecuteBatchRequestObject) (server.ExecuteBatchResponseObject, error) {
+	commands := make([]any, len(request.Body.Commands))
+	for i, c := range request.Body.Commands {
+		command, err := toCommand(c)
+		if err != nil {
+			return server.ExecuteBatchdefaultJSONResponse{Body: server.Error{Message: fmt.Sprintf("command %d: %s", i, err.Error())}, StatusCode: http.StatusBadRequest}, nil
+		}
+		commands[i] = command
+	}
+	ctx, cancel := h.withTimeout(ctx)
+	defer cancel()
+	results, err := h.db.ExecuteBatchContext(ctx, commands)
+	if err != nil {
+		return server.ExecuteBatchdefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: commandErrorStatus(err)}, nil
+	}
+	response := make(server.ExecuteBatch200JSONResponse, len(results))
+	for i, res := range results {
+		if request.Body.Commands[i].Type == server.Select {
+			rows := make(server.Rows, len(*res))
+			for j, row := range *res {
+				rows[j] = ColumnSetToRows(row)
+			}
+			response[i].Rows = &rows
+			continue
+		}
+		if message, ok := (*res)[0]["message"].(string); ok {
+			response[i].Message = &message
+		}
+	}
+	return response, nil
+}
+
+// Convert command of request to db command
+func toCommand(c server.Command) (any, error) {
+	switch c.Type {
+	case server.CreateTable:
+		if c.Schema == nil {
+			return nil, fmt.Errorf("schema is required")
+		}
+		tableSchema := schema.T{}
+		for _, s := range *c.Schema {
+			tableSchema[s.Column] = dbtypes.Type(s.Type)
+		}
+		command := &db.CommandCreateTable{Name: c.Table, Schema: tableSchema}
+		if c.Engine != nil {
+			command.Engine = table.EngineKind(*c.Engine)
+		}
+		return command, nil
+	case server.DropTable:
+		return &db.CommandDropTable{Name: c.Table}, nil
+	case server.Insert:
+		if c.Rows == nil {
+			return nil, fmt.Errorf("rows are required")
+		}
+		data := make([]table.ColumnSet, len(*c.Rows))
+		for i, v := range *c.Rows {
+			data[i] = RowToColumnSet(v)
+		}
+		return &db.CommandInsert{To: c.Table, Data: &data}, nil
+	case server.Select:
+		columns := []string{}
+		if c.Columns != nil {
+			columns = *c.Columns
+		}
+		return &db.CommandSelect{From: c.Table, Fields: &columns, Conditions: optionalConditions(c.Conditions)}, nil
+	case server.Update:
+		if c.Data == nil {
+			return nil, fmt.Errorf("data is required")
+		}
+		return &db.CommandUpdate{TableName: c.Table, Conditions: optionalConditions(c.Conditions), Data: RowToColumnSet(*c.Data), ExpectedVersion: c.Version}, nil
+	case server.Delete:
+		return &db.CommandDelete{From: c.Table, Conditions: optionalConditions(c.Conditions), ExpectedVersion: c.Version}, nil
+	case server.RemoveDuplicates:
+		return &db.CommandRemoveDuplicates{From: c.Table}, nil
+	default:
+		return nil, fmt.Errorf("unknown command type %s", c.Type)
+	}
+}
+
+// Omitted conditions are empty, so they match all rows
+func optionalConditions(conditions *server.Conditions) table.ColumnSet {
+	if conditions == nil {
+		return table.ColumnSet{}
+	}
+	return ConditionsToColumnSet(*conditions)
+}
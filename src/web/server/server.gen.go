@@ -10,16 +10,35 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/deepmap/oapi-codegen/pkg/runtime"
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/labstack/echo/v4"
 )
 
+// Defines values for CommandEngine.
+const (
+	CommandEngineDisk   CommandEngine = "disk"
+	CommandEngineMemory CommandEngine = "memory"
+)
+
+// Defines values for CommandType.
+const (
+	CreateTable      CommandType = "createTable"
+	Delete           CommandType = "delete"
+	DropTable        CommandType = "dropTable"
+	Insert           CommandType = "insert"
+	RemoveDuplicates CommandType = "removeDuplicates"
+	Select           CommandType = "select"
+	Update           CommandType = "update"
+)
+
 // Defines values for SchemaType.
 const (
 	Char    SchemaType = "char"
@@ -30,19 +49,95 @@ const (
 	String  SchemaType = "string"
 )
 
+// Defines values for TableSchemaEngine.
+const (
+	TableSchemaEngineDisk   TableSchemaEngine = "disk"
+	TableSchemaEngineMemory TableSchemaEngine = "memory"
+)
+
+// BatchBody defines model for BatchBody.
+type BatchBody struct {
+	Commands []Command `json:"commands"`
+}
+
+// Command command of batch, fields used depend on type
+type Command struct {
+	Columns *RowNames `json:"columns,omitempty"`
+
+	// Conditions Rows, that match every condition. Column maps to value, that column must be equal to, or to object of operators eq, ne, gt, gte, lt, lte, in, notIn and between with operands, e.g. {"leg_length": {"gt": 3.5}, "id": {"in": [1, 2]}, "weight": {"between": [1, 2]}}. Operands of in, notIn and between are lists. Operators gt, gte, lt, lte and between are not supported by realInv and image columns. Keys $anyOf and $allOf compose non empty lists of nested conditions, at least one or all of them must match, key $not holds nested condition, that must not match, e.g. {"$anyOf": [{"species": "tree"}, {"jump": {"gt": 2}, "weight": {"lt": 1}}]}.
+	Conditions *Conditions `json:"conditions,omitempty"`
+	Data       *Row        `json:"data,omitempty"`
+
+	// Engine storage engine of created table, memory by default
+	Engine *CommandEngine `json:"engine,omitempty"`
+	Rows   *Rows          `json:"rows,omitempty"`
+
+	// Schema columns of created table
+	Schema *[]Schema   `json:"schema,omitempty"`
+	Table  string      `json:"table"`
+	Type   CommandType `json:"type"`
+
+	// Version version, that rows changed by update or delete must have, batch fails with status 412 otherwise
+	Version *int64 `json:"version,omitempty"`
+}
+
+// CommandEngine storage engine of created table, memory by default
+type CommandEngine string
+
+// CommandType defines model for Command.Type.
+type CommandType string
+
+// CommandResult defines model for CommandResult.
+type CommandResult struct {
+	Message *string `json:"message,omitempty"`
+	Rows    *Rows   `json:"rows,omitempty"`
+}
+
+// Conditions Rows, that match every condition. Column maps to value, that column must be equal to, or to object of operators eq, ne, gt, gte, lt, lte, in, notIn and between with operands, e.g. {"leg_length": {"gt": 3.5}, "id": {"in": [1, 2]}, "weight": {"between": [1, 2]}}. Operands of in, notIn and between are lists. Operators gt, gte, lt, lte and between are not supported by realInv and image columns. Keys $anyOf and $allOf compose non empty lists of nested conditions, at least one or all of them must match, key $not holds nested condition, that must not match, e.g. {"$anyOf": [{"species": "tree"}, {"jump": {"gt": 2}, "weight": {"lt": 1}}]}.
+type Conditions map[string]interface{}
+
 // DbSchema defines model for DbSchema.
 type DbSchema = []TableSchema
 
+// DumpStatus defines model for DumpStatus.
+type DumpStatus struct {
+	// Failures failed dumps since start
+	Failures int64 `json:"failures"`
+
+	// LastDump time of the latest stored dump
+	LastDump      *time.Time `json:"lastDump,omitempty"`
+	LastError     *string    `json:"lastError,omitempty"`
+	LastErrorTime *time.Time `json:"lastErrorTime,omitempty"`
+
+	// Size size of the latest stored dump in bytes
+	Size   *int64 `json:"size,omitempty"`
+	Tables *int   `json:"tables,omitempty"`
+}
+
 // Error defines model for Error.
 type Error struct {
 	Message string `json:"message"`
 }
 
+// ImportResult defines model for ImportResult.
+type ImportResult struct {
+	// Errors errors of the first skipped lines
+	Errors   []LineError `json:"errors"`
+	Inserted int         `json:"inserted"`
+	Skipped  int         `json:"skipped"`
+}
+
 // Info defines model for Info.
 type Info struct {
 	Message string `json:"message"`
 }
 
+// LineError defines model for LineError.
+type LineError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
 // Row defines model for Row.
 type Row map[string]interface{}
 
@@ -63,22 +158,92 @@ type SchemaType string
 
 // SelectBody defines model for SelectBody.
 type SelectBody struct {
-	Columns    RowNames `json:"columns"`
-	Conditions Row      `json:"conditions"`
+	Columns RowNames `json:"columns"`
+
+	// Conditions Rows, that match every condition. Column maps to value, that column must be equal to, or to object of operators eq, ne, gt, gte, lt, lte, in, notIn and between with operands, e.g. {"leg_length": {"gt": 3.5}, "id": {"in": [1, 2]}, "weight": {"between": [1, 2]}}. Operands of in, notIn and between are lists. Operators gt, gte, lt, lte and between are not supported by realInv and image columns. Keys $anyOf and $allOf compose non empty lists of nested conditions, at least one or all of them must match, key $not holds nested condition, that must not match, e.g. {"$anyOf": [{"species": "tree"}, {"jump": {"gt": 2}, "weight": {"lt": 1}}]}.
+	Conditions Conditions `json:"conditions"`
 }
 
 // TableSchema defines model for TableSchema.
 type TableSchema struct {
-	Schema    *[]Schema `json:"schema,omitempty"`
-	TableName *string   `json:"tableName,omitempty"`
+	// Engine storage engine of table, memory by default
+	Engine    *TableSchemaEngine `json:"engine,omitempty"`
+	Schema    *[]Schema          `json:"schema,omitempty"`
+	TableName *string            `json:"tableName,omitempty"`
+}
+
+// TableSchemaEngine storage engine of table, memory by default
+type TableSchemaEngine string
+
+// Transaction defines model for Transaction.
+type Transaction struct {
+	Id string `json:"id"`
 }
 
 // UpdateBody defines model for UpdateBody.
 type UpdateBody struct {
-	Conditions Row `json:"conditions"`
-	Data       Row `json:"data"`
+	// Conditions Rows, that match every condition. Column maps to value, that column must be equal to, or to object of operators eq, ne, gt, gte, lt, lte, in, notIn and between with operands, e.g. {"leg_length": {"gt": 3.5}, "id": {"in": [1, 2]}, "weight": {"between": [1, 2]}}. Operands of in, notIn and between are lists. Operators gt, gte, lt, lte and between are not supported by realInv and image columns. Keys $anyOf and $allOf compose non empty lists of nested conditions, at least one or all of them must match, key $not holds nested condition, that must not match, e.g. {"$anyOf": [{"species": "tree"}, {"jump": {"gt": 2}, "weight": {"lt": 1}}]}.
+	Conditions Conditions `json:"conditions"`
+	Data       Row        `json:"data"`
+}
+
+// ExpectedVersion defines model for ExpectedVersion.
+type ExpectedVersion = int64
+
+// TransactionId defines model for TransactionId.
+type TransactionId = string
+
+// DeleteTableParams defines parameters for DeleteTable.
+type DeleteTableParams struct {
+	// XTransactionId id of transaction to execute command within, command is executed alone if omitted
+	XTransactionId *TransactionId `json:"X-Transaction-Id,omitempty"`
+}
+
+// CreateTableParams defines parameters for CreateTable.
+type CreateTableParams struct {
+	// XTransactionId id of transaction to execute command within, command is executed alone if omitted
+	XTransactionId *TransactionId `json:"X-Transaction-Id,omitempty"`
+}
+
+// UpdateRowsParams defines parameters for UpdateRows.
+type UpdateRowsParams struct {
+	// Version version, that changed rows must have, command fails with status 412 otherwise
+	Version *ExpectedVersion `form:"version,omitempty" json:"version,omitempty"`
+
+	// XTransactionId id of transaction to execute command within, command is executed alone if omitted
+	XTransactionId *TransactionId `json:"X-Transaction-Id,omitempty"`
+}
+
+// InsertRowsParams defines parameters for InsertRows.
+type InsertRowsParams struct {
+	// XTransactionId id of transaction to execute command within, command is executed alone if omitted
+	XTransactionId *TransactionId `json:"X-Transaction-Id,omitempty"`
+}
+
+// DeleteRowsParams defines parameters for DeleteRows.
+type DeleteRowsParams struct {
+	// Version version, that changed rows must have, command fails with status 412 otherwise
+	Version *ExpectedVersion `form:"version,omitempty" json:"version,omitempty"`
+
+	// XTransactionId id of transaction to execute command within, command is executed alone if omitted
+	XTransactionId *TransactionId `json:"X-Transaction-Id,omitempty"`
+}
+
+// DeleteDuplicateRowsParams defines parameters for DeleteDuplicateRows.
+type DeleteDuplicateRowsParams struct {
+	// XTransactionId id of transaction to execute command within, command is executed alone if omitted
+	XTransactionId *TransactionId `json:"X-Transaction-Id,omitempty"`
+}
+
+// SelectRowsParams defines parameters for SelectRows.
+type SelectRowsParams struct {
+	// XTransactionId id of transaction to execute command within, command is executed alone if omitted
+	XTransactionId *TransactionId `json:"X-Transaction-Id,omitempty"`
 }
 
+// ExecuteBatchJSONRequestBody defines body for ExecuteBatch for application/json ContentType.
+type ExecuteBatchJSONRequestBody = BatchBody
+
 // CreateTableJSONRequestBody defines body for CreateTable for application/json ContentType.
 type CreateTableJSONRequestBody = TableSchema
 
@@ -89,7 +254,7 @@ type UpdateRowsJSONRequestBody = UpdateBody
 type InsertRowsJSONRequestBody = Rows
 
 // DeleteRowsJSONRequestBody defines body for DeleteRows for application/json ContentType.
-type DeleteRowsJSONRequestBody = Row
+type DeleteRowsJSONRequestBody = Conditions
 
 // SelectRowsJSONRequestBody defines body for SelectRows for application/json ContentType.
 type SelectRowsJSONRequestBody = SelectBody
@@ -100,26 +265,65 @@ type ServerInterface interface {
 	// (GET /.schema)
 	DbSchema(ctx echo.Context) error
 
+	// (GET /.sql)
+	ExportSql(ctx echo.Context) error
+
+	// (GET /admin/dump)
+	DownloadDump(ctx echo.Context) error
+
+	// (POST /admin/dump)
+	StoreDump(ctx echo.Context) error
+
+	// (PUT /admin/dump)
+	RestoreDump(ctx echo.Context) error
+
+	// (GET /admin/dump/status)
+	DumpStatus(ctx echo.Context) error
+
+	// (POST /batch)
+	ExecuteBatch(ctx echo.Context) error
+
 	// (POST /delete-table/{name}/)
-	DeleteTable(ctx echo.Context, name string) error
+	DeleteTable(ctx echo.Context, name string, params DeleteTableParams) error
 
 	// (POST /table)
-	CreateTable(ctx echo.Context) error
+	CreateTable(ctx echo.Context, params CreateTableParams) error
 
 	// (PATCH /table/{name})
-	UpdateRows(ctx echo.Context, name string) error
+	UpdateRows(ctx echo.Context, name string, params UpdateRowsParams) error
 
 	// (POST /table/{name})
-	InsertRows(ctx echo.Context, name string) error
+	InsertRows(ctx echo.Context, name string, params InsertRowsParams) error
+
+	// (GET /table/{name}/csv)
+	ExportCsv(ctx echo.Context, name string) error
+
+	// (POST /table/{name}/csv)
+	ImportCsv(ctx echo.Context, name string) error
 
 	// (POST /table/{name}/delete)
-	DeleteRows(ctx echo.Context, name string) error
+	DeleteRows(ctx echo.Context, name string, params DeleteRowsParams) error
+
+	// (GET /table/{name}/export)
+	ExportRows(ctx echo.Context, name string) error
+
+	// (POST /table/{name}/import)
+	ImportRows(ctx echo.Context, name string) error
 
 	// (POST /table/{name}/remove-duplicates)
-	DeleteDuplicateRows(ctx echo.Context, name string) error
+	DeleteDuplicateRows(ctx echo.Context, name string, params DeleteDuplicateRowsParams) error
 
 	// (POST /table/{name}/select)
-	SelectRows(ctx echo.Context, name string) error
+	SelectRows(ctx echo.Context, name string, params SelectRowsParams) error
+
+	// (POST /tx)
+	BeginTransaction(ctx echo.Context) error
+
+	// (POST /tx/{id}/commit)
+	CommitTransaction(ctx echo.Context, id string) error
+
+	// (POST /tx/{id}/rollback)
+	RollbackTransaction(ctx echo.Context, id string) error
 }
 
 // ServerInterfaceWrapper converts echo contexts to parameters.
@@ -136,6 +340,60 @@ func (w *ServerInterfaceWrapper) DbSchema(ctx echo.Context) error {
 	return err
 }
 
+// ExportSql converts echo context to params.
+func (w *ServerInterfaceWrapper) ExportSql(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ExportSql(ctx)
+	return err
+}
+
+// DownloadDump converts echo context to params.
+func (w *ServerInterfaceWrapper) DownloadDump(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.DownloadDump(ctx)
+	return err
+}
+
+// StoreDump converts echo context to params.
+func (w *ServerInterfaceWrapper) StoreDump(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.StoreDump(ctx)
+	return err
+}
+
+// RestoreDump converts echo context to params.
+func (w *ServerInterfaceWrapper) RestoreDump(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.RestoreDump(ctx)
+	return err
+}
+
+// DumpStatus converts echo context to params.
+func (w *ServerInterfaceWrapper) DumpStatus(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.DumpStatus(ctx)
+	return err
+}
+
+// ExecuteBatch converts echo context to params.
+func (w *ServerInterfaceWrapper) ExecuteBatch(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ExecuteBatch(ctx)
+	return err
+}
+
 // DeleteTable converts echo context to params.
 func (w *ServerInterfaceWrapper) DeleteTable(ctx echo.Context) error {
 	var err error
@@ -147,8 +405,28 @@ func (w *ServerInterfaceWrapper) DeleteTable(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter name: %s", err))
 	}
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params DeleteTableParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "X-Transaction-Id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Transaction-Id")]; found {
+		var XTransactionId TransactionId
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for X-Transaction-Id, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Transaction-Id", runtime.ParamLocationHeader, valueList[0], &XTransactionId)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter X-Transaction-Id: %s", err))
+		}
+
+		params.XTransactionId = &XTransactionId
+	}
+
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.DeleteTable(ctx, name)
+	err = w.Handler.DeleteTable(ctx, name, params)
 	return err
 }
 
@@ -156,8 +434,28 @@ func (w *ServerInterfaceWrapper) DeleteTable(ctx echo.Context) error {
 func (w *ServerInterfaceWrapper) CreateTable(ctx echo.Context) error {
 	var err error
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params CreateTableParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "X-Transaction-Id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Transaction-Id")]; found {
+		var XTransactionId TransactionId
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for X-Transaction-Id, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Transaction-Id", runtime.ParamLocationHeader, valueList[0], &XTransactionId)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter X-Transaction-Id: %s", err))
+		}
+
+		params.XTransactionId = &XTransactionId
+	}
+
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.CreateTable(ctx)
+	err = w.Handler.CreateTable(ctx, params)
 	return err
 }
 
@@ -172,8 +470,34 @@ func (w *ServerInterfaceWrapper) UpdateRows(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter name: %s", err))
 	}
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params UpdateRowsParams
+	// ------------- Optional query parameter "version" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "version", ctx.QueryParams(), &params.Version)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter version: %s", err))
+	}
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "X-Transaction-Id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Transaction-Id")]; found {
+		var XTransactionId TransactionId
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for X-Transaction-Id, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Transaction-Id", runtime.ParamLocationHeader, valueList[0], &XTransactionId)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter X-Transaction-Id: %s", err))
+		}
+
+		params.XTransactionId = &XTransactionId
+	}
+
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.UpdateRows(ctx, name)
+	err = w.Handler.UpdateRows(ctx, name, params)
 	return err
 }
 
@@ -188,8 +512,60 @@ func (w *ServerInterfaceWrapper) InsertRows(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter name: %s", err))
 	}
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params InsertRowsParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "X-Transaction-Id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Transaction-Id")]; found {
+		var XTransactionId TransactionId
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for X-Transaction-Id, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Transaction-Id", runtime.ParamLocationHeader, valueList[0], &XTransactionId)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter X-Transaction-Id: %s", err))
+		}
+
+		params.XTransactionId = &XTransactionId
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.InsertRows(ctx, name, params)
+	return err
+}
+
+// ExportCsv converts echo context to params.
+func (w *ServerInterfaceWrapper) ExportCsv(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "name" -------------
+	var name string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "name", runtime.ParamLocationPath, ctx.Param("name"), &name)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter name: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ExportCsv(ctx, name)
+	return err
+}
+
+// ImportCsv converts echo context to params.
+func (w *ServerInterfaceWrapper) ImportCsv(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "name" -------------
+	var name string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "name", runtime.ParamLocationPath, ctx.Param("name"), &name)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter name: %s", err))
+	}
+
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.InsertRows(ctx, name)
+	err = w.Handler.ImportCsv(ctx, name)
 	return err
 }
 
@@ -204,8 +580,66 @@ func (w *ServerInterfaceWrapper) DeleteRows(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter name: %s", err))
 	}
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params DeleteRowsParams
+	// ------------- Optional query parameter "version" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "version", ctx.QueryParams(), &params.Version)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter version: %s", err))
+	}
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "X-Transaction-Id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Transaction-Id")]; found {
+		var XTransactionId TransactionId
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for X-Transaction-Id, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Transaction-Id", runtime.ParamLocationHeader, valueList[0], &XTransactionId)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter X-Transaction-Id: %s", err))
+		}
+
+		params.XTransactionId = &XTransactionId
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.DeleteRows(ctx, name, params)
+	return err
+}
+
+// ExportRows converts echo context to params.
+func (w *ServerInterfaceWrapper) ExportRows(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "name" -------------
+	var name string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "name", runtime.ParamLocationPath, ctx.Param("name"), &name)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter name: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.ExportRows(ctx, name)
+	return err
+}
+
+// ImportRows converts echo context to params.
+func (w *ServerInterfaceWrapper) ImportRows(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "name" -------------
+	var name string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "name", runtime.ParamLocationPath, ctx.Param("name"), &name)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter name: %s", err))
+	}
+
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.DeleteRows(ctx, name)
+	err = w.Handler.ImportRows(ctx, name)
 	return err
 }
 
@@ -220,8 +654,28 @@ func (w *ServerInterfaceWrapper) DeleteDuplicateRows(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter name: %s", err))
 	}
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params DeleteDuplicateRowsParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "X-Transaction-Id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Transaction-Id")]; found {
+		var XTransactionId TransactionId
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for X-Transaction-Id, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Transaction-Id", runtime.ParamLocationHeader, valueList[0], &XTransactionId)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter X-Transaction-Id: %s", err))
+		}
+
+		params.XTransactionId = &XTransactionId
+	}
+
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.DeleteDuplicateRows(ctx, name)
+	err = w.Handler.DeleteDuplicateRows(ctx, name, params)
 	return err
 }
 
@@ -236,8 +690,69 @@ func (w *ServerInterfaceWrapper) SelectRows(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter name: %s", err))
 	}
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params SelectRowsParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "X-Transaction-Id" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("X-Transaction-Id")]; found {
+		var XTransactionId TransactionId
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for X-Transaction-Id, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithLocation("simple", false, "X-Transaction-Id", runtime.ParamLocationHeader, valueList[0], &XTransactionId)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter X-Transaction-Id: %s", err))
+		}
+
+		params.XTransactionId = &XTransactionId
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.SelectRows(ctx, name, params)
+	return err
+}
+
+// BeginTransaction converts echo context to params.
+func (w *ServerInterfaceWrapper) BeginTransaction(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.BeginTransaction(ctx)
+	return err
+}
+
+// CommitTransaction converts echo context to params.
+func (w *ServerInterfaceWrapper) CommitTransaction(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.CommitTransaction(ctx, id)
+	return err
+}
+
+// RollbackTransaction converts echo context to params.
+func (w *ServerInterfaceWrapper) RollbackTransaction(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "id", runtime.ParamLocationPath, ctx.Param("id"), &id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
 	// Invoke the callback with all the unmarshalled arguments
-	err = w.Handler.SelectRows(ctx, name)
+	err = w.Handler.RollbackTransaction(ctx, id)
 	return err
 }
 
@@ -270,13 +785,26 @@ func RegisterHandlersWithBaseURL(router EchoRouter, si ServerInterface, baseURL
 	}
 
 	router.GET(baseURL+"/.schema", wrapper.DbSchema)
+	router.GET(baseURL+"/.sql", wrapper.ExportSql)
+	router.GET(baseURL+"/admin/dump", wrapper.DownloadDump)
+	router.POST(baseURL+"/admin/dump", wrapper.StoreDump)
+	router.PUT(baseURL+"/admin/dump", wrapper.RestoreDump)
+	router.GET(baseURL+"/admin/dump/status", wrapper.DumpStatus)
+	router.POST(baseURL+"/batch", wrapper.ExecuteBatch)
 	router.POST(baseURL+"/delete-table/:name/", wrapper.DeleteTable)
 	router.POST(baseURL+"/table", wrapper.CreateTable)
 	router.PATCH(baseURL+"/table/:name", wrapper.UpdateRows)
 	router.POST(baseURL+"/table/:name", wrapper.InsertRows)
+	router.GET(baseURL+"/table/:name/csv", wrapper.ExportCsv)
+	router.POST(baseURL+"/table/:name/csv", wrapper.ImportCsv)
 	router.POST(baseURL+"/table/:name/delete", wrapper.DeleteRows)
+	router.GET(baseURL+"/table/:name/export", wrapper.ExportRows)
+	router.POST(baseURL+"/table/:name/import", wrapper.ImportRows)
 	router.POST(baseURL+"/table/:name/remove-duplicates", wrapper.DeleteDuplicateRows)
 	router.POST(baseURL+"/table/:name/select", wrapper.SelectRows)
+	router.POST(baseURL+"/tx", wrapper.BeginTransaction)
+	router.POST(baseURL+"/tx/:id/commit", wrapper.CommitTransaction)
+	router.POST(baseURL+"/tx/:id/rollback", wrapper.RollbackTransaction)
 
 }
 
@@ -308,47 +836,248 @@ func (response DbSchemadefaultJSONResponse) VisitDbSchemaResponse(w http.Respons
 	return json.NewEncoder(w).Encode(response.Body)
 }
 
-type DeleteTableRequestObject struct {
-	Name string `json:"name"`
+type ExportSqlRequestObject struct {
 }
 
-type DeleteTableResponseObject interface {
-	VisitDeleteTableResponse(w http.ResponseWriter) error
+type ExportSqlResponseObject interface {
+	VisitExportSqlResponse(w http.ResponseWriter) error
 }
 
-type DeleteTable200JSONResponse Info
+type ExportSql200ApplicationsqlResponse struct {
+	Body          io.Reader
+	ContentLength int64
+}
 
-func (response DeleteTable200JSONResponse) VisitDeleteTableResponse(w http.ResponseWriter) error {
-	w.Header().Set("Content-Type", "application/json")
+func (response ExportSql200ApplicationsqlResponse) VisitExportSqlResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/sql")
+	if response.ContentLength != 0 {
+		w.Header().Set("Content-Length", fmt.Sprint(response.ContentLength))
+	}
 	w.WriteHeader(200)
 
-	return json.NewEncoder(w).Encode(response)
+	if closer, ok := response.Body.(io.ReadCloser); ok {
+		defer closer.Close()
+	}
+	_, err := io.Copy(w, response.Body)
+	return err
 }
 
-type DeleteTabledefaultJSONResponse struct {
+type ExportSqldefaultJSONResponse struct {
 	Body       Error
 	StatusCode int
 }
 
-func (response DeleteTabledefaultJSONResponse) VisitDeleteTableResponse(w http.ResponseWriter) error {
+func (response ExportSqldefaultJSONResponse) VisitExportSqlResponse(w http.ResponseWriter) error {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(response.StatusCode)
 
 	return json.NewEncoder(w).Encode(response.Body)
 }
 
-type CreateTableRequestObject struct {
-	Body *CreateTableJSONRequestBody
+type DownloadDumpRequestObject struct {
 }
 
-type CreateTableResponseObject interface {
-	VisitCreateTableResponse(w http.ResponseWriter) error
+type DownloadDumpResponseObject interface {
+	VisitDownloadDumpResponse(w http.ResponseWriter) error
 }
 
-type CreateTable200JSONResponse Info
+type DownloadDump200ApplicationoctetStreamResponse struct {
+	Body          io.Reader
+	ContentLength int64
+}
 
-func (response CreateTable200JSONResponse) VisitCreateTableResponse(w http.ResponseWriter) error {
-	w.Header().Set("Content-Type", "application/json")
+func (response DownloadDump200ApplicationoctetStreamResponse) VisitDownloadDumpResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if response.ContentLength != 0 {
+		w.Header().Set("Content-Length", fmt.Sprint(response.ContentLength))
+	}
+	w.WriteHeader(200)
+
+	if closer, ok := response.Body.(io.ReadCloser); ok {
+		defer closer.Close()
+	}
+	_, err := io.Copy(w, response.Body)
+	return err
+}
+
+type DownloadDumpdefaultJSONResponse struct {
+	Body       Error
+	StatusCode int
+}
+
+func (response DownloadDumpdefaultJSONResponse) VisitDownloadDumpResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.StatusCode)
+
+	return json.NewEncoder(w).Encode(response.Body)
+}
+
+type StoreDumpRequestObject struct {
+}
+
+type StoreDumpResponseObject interface {
+	VisitStoreDumpResponse(w http.ResponseWriter) error
+}
+
+type StoreDump200JSONResponse Info
+
+func (response StoreDump200JSONResponse) VisitStoreDumpResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type StoreDumpdefaultJSONResponse struct {
+	Body       Error
+	StatusCode int
+}
+
+func (response StoreDumpdefaultJSONResponse) VisitStoreDumpResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.StatusCode)
+
+	return json.NewEncoder(w).Encode(response.Body)
+}
+
+type RestoreDumpRequestObject struct {
+	Body io.Reader
+}
+
+type RestoreDumpResponseObject interface {
+	VisitRestoreDumpResponse(w http.ResponseWriter) error
+}
+
+type RestoreDump200JSONResponse Info
+
+func (response RestoreDump200JSONResponse) VisitRestoreDumpResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type RestoreDumpdefaultJSONResponse struct {
+	Body       Error
+	StatusCode int
+}
+
+func (response RestoreDumpdefaultJSONResponse) VisitRestoreDumpResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.StatusCode)
+
+	return json.NewEncoder(w).Encode(response.Body)
+}
+
+type DumpStatusRequestObject struct {
+}
+
+type DumpStatusResponseObject interface {
+	VisitDumpStatusResponse(w http.ResponseWriter) error
+}
+
+type DumpStatus200JSONResponse DumpStatus
+
+func (response DumpStatus200JSONResponse) VisitDumpStatusResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type DumpStatusdefaultJSONResponse struct {
+	Body       Error
+	StatusCode int
+}
+
+func (response DumpStatusdefaultJSONResponse) VisitDumpStatusResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.StatusCode)
+
+	return json.NewEncoder(w).Encode(response.Body)
+}
+
+type ExecuteBatchRequestObject struct {
+	Body *ExecuteBatchJSONRequestBody
+}
+
+type ExecuteBatchResponseObject interface {
+	VisitExecuteBatchResponse(w http.ResponseWriter) error
+}
+
+type ExecuteBatch200JSONResponse []CommandResult
+
+func (response ExecuteBatch200JSONResponse) VisitExecuteBatchResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ExecuteBatch412JSONResponse Error
+
+func (response ExecuteBatch412JSONResponse) VisitExecuteBatchResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(412)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ExecuteBatchdefaultJSONResponse struct {
+	Body       Error
+	StatusCode int
+}
+
+func (response ExecuteBatchdefaultJSONResponse) VisitExecuteBatchResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.StatusCode)
+
+	return json.NewEncoder(w).Encode(response.Body)
+}
+
+type DeleteTableRequestObject struct {
+	Name   string `json:"name"`
+	Params DeleteTableParams
+}
+
+type DeleteTableResponseObject interface {
+	VisitDeleteTableResponse(w http.ResponseWriter) error
+}
+
+type DeleteTable200JSONResponse Info
+
+func (response DeleteTable200JSONResponse) VisitDeleteTableResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type DeleteTabledefaultJSONResponse struct {
+	Body       Error
+	StatusCode int
+}
+
+func (response DeleteTabledefaultJSONResponse) VisitDeleteTableResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.StatusCode)
+
+	return json.NewEncoder(w).Encode(response.Body)
+}
+
+type CreateTableRequestObject struct {
+	Params CreateTableParams
+	Body   *CreateTableJSONRequestBody
+}
+
+type CreateTableResponseObject interface {
+	VisitCreateTableResponse(w http.ResponseWriter) error
+}
+
+type CreateTable200JSONResponse Info
+
+func (response CreateTable200JSONResponse) VisitCreateTableResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(200)
 
 	return json.NewEncoder(w).Encode(response)
@@ -367,8 +1096,9 @@ func (response CreateTabledefaultJSONResponse) VisitCreateTableResponse(w http.R
 }
 
 type UpdateRowsRequestObject struct {
-	Name string `json:"name"`
-	Body *UpdateRowsJSONRequestBody
+	Name   string `json:"name"`
+	Params UpdateRowsParams
+	Body   *UpdateRowsJSONRequestBody
 }
 
 type UpdateRowsResponseObject interface {
@@ -384,6 +1114,15 @@ func (response UpdateRows200JSONResponse) VisitUpdateRowsResponse(w http.Respons
 	return json.NewEncoder(w).Encode(response)
 }
 
+type UpdateRows412JSONResponse Error
+
+func (response UpdateRows412JSONResponse) VisitUpdateRowsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(412)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
 type UpdateRowsdefaultJSONResponse struct {
 	Body       Error
 	StatusCode int
@@ -397,8 +1136,9 @@ func (response UpdateRowsdefaultJSONResponse) VisitUpdateRowsResponse(w http.Res
 }
 
 type InsertRowsRequestObject struct {
-	Name string `json:"name"`
-	Body *InsertRowsJSONRequestBody
+	Name   string `json:"name"`
+	Params InsertRowsParams
+	Body   *InsertRowsJSONRequestBody
 }
 
 type InsertRowsResponseObject interface {
@@ -426,9 +1166,79 @@ func (response InsertRowsdefaultJSONResponse) VisitInsertRowsResponse(w http.Res
 	return json.NewEncoder(w).Encode(response.Body)
 }
 
-type DeleteRowsRequestObject struct {
+type ExportCsvRequestObject struct {
 	Name string `json:"name"`
-	Body *DeleteRowsJSONRequestBody
+}
+
+type ExportCsvResponseObject interface {
+	VisitExportCsvResponse(w http.ResponseWriter) error
+}
+
+type ExportCsv200TextcsvResponse struct {
+	Body          io.Reader
+	ContentLength int64
+}
+
+func (response ExportCsv200TextcsvResponse) VisitExportCsvResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "text/csv")
+	if response.ContentLength != 0 {
+		w.Header().Set("Content-Length", fmt.Sprint(response.ContentLength))
+	}
+	w.WriteHeader(200)
+
+	if closer, ok := response.Body.(io.ReadCloser); ok {
+		defer closer.Close()
+	}
+	_, err := io.Copy(w, response.Body)
+	return err
+}
+
+type ExportCsvdefaultJSONResponse struct {
+	Body       Error
+	StatusCode int
+}
+
+func (response ExportCsvdefaultJSONResponse) VisitExportCsvResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.StatusCode)
+
+	return json.NewEncoder(w).Encode(response.Body)
+}
+
+type ImportCsvRequestObject struct {
+	Name string `json:"name"`
+	Body io.Reader
+}
+
+type ImportCsvResponseObject interface {
+	VisitImportCsvResponse(w http.ResponseWriter) error
+}
+
+type ImportCsv200JSONResponse ImportResult
+
+func (response ImportCsv200JSONResponse) VisitImportCsvResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ImportCsvdefaultJSONResponse struct {
+	Body       Error
+	StatusCode int
+}
+
+func (response ImportCsvdefaultJSONResponse) VisitImportCsvResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.StatusCode)
+
+	return json.NewEncoder(w).Encode(response.Body)
+}
+
+type DeleteRowsRequestObject struct {
+	Name   string `json:"name"`
+	Params DeleteRowsParams
+	Body   *DeleteRowsJSONRequestBody
 }
 
 type DeleteRowsResponseObject interface {
@@ -444,6 +1254,15 @@ func (response DeleteRows200JSONResponse) VisitDeleteRowsResponse(w http.Respons
 	return json.NewEncoder(w).Encode(response)
 }
 
+type DeleteRows412JSONResponse Error
+
+func (response DeleteRows412JSONResponse) VisitDeleteRowsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(412)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
 type DeleteRowsdefaultJSONResponse struct {
 	Body       Error
 	StatusCode int
@@ -456,59 +1275,216 @@ func (response DeleteRowsdefaultJSONResponse) VisitDeleteRowsResponse(w http.Res
 	return json.NewEncoder(w).Encode(response.Body)
 }
 
-type DeleteDuplicateRowsRequestObject struct {
-	Name string `json:"name"`
+type ExportRowsRequestObject struct {
+	Name string `json:"name"`
+}
+
+type ExportRowsResponseObject interface {
+	VisitExportRowsResponse(w http.ResponseWriter) error
+}
+
+type ExportRows200ApplicationxNdjsonResponse struct {
+	Body          io.Reader
+	ContentLength int64
+}
+
+func (response ExportRows200ApplicationxNdjsonResponse) VisitExportRowsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	if response.ContentLength != 0 {
+		w.Header().Set("Content-Length", fmt.Sprint(response.ContentLength))
+	}
+	w.WriteHeader(200)
+
+	if closer, ok := response.Body.(io.ReadCloser); ok {
+		defer closer.Close()
+	}
+	_, err := io.Copy(w, response.Body)
+	return err
+}
+
+type ExportRowsdefaultJSONResponse struct {
+	Body       Error
+	StatusCode int
+}
+
+func (response ExportRowsdefaultJSONResponse) VisitExportRowsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.StatusCode)
+
+	return json.NewEncoder(w).Encode(response.Body)
+}
+
+type ImportRowsRequestObject struct {
+	Name string `json:"name"`
+	Body io.Reader
+}
+
+type ImportRowsResponseObject interface {
+	VisitImportRowsResponse(w http.ResponseWriter) error
+}
+
+type ImportRows200JSONResponse ImportResult
+
+func (response ImportRows200JSONResponse) VisitImportRowsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ImportRowsdefaultJSONResponse struct {
+	Body       Error
+	StatusCode int
+}
+
+func (response ImportRowsdefaultJSONResponse) VisitImportRowsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.StatusCode)
+
+	return json.NewEncoder(w).Encode(response.Body)
+}
+
+type DeleteDuplicateRowsRequestObject struct {
+	Name   string `json:"name"`
+	Params DeleteDuplicateRowsParams
+}
+
+type DeleteDuplicateRowsResponseObject interface {
+	VisitDeleteDuplicateRowsResponse(w http.ResponseWriter) error
+}
+
+type DeleteDuplicateRows200JSONResponse Info
+
+func (response DeleteDuplicateRows200JSONResponse) VisitDeleteDuplicateRowsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type DeleteDuplicateRowsdefaultJSONResponse struct {
+	Body       Error
+	StatusCode int
+}
+
+func (response DeleteDuplicateRowsdefaultJSONResponse) VisitDeleteDuplicateRowsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.StatusCode)
+
+	return json.NewEncoder(w).Encode(response.Body)
+}
+
+type SelectRowsRequestObject struct {
+	Name   string `json:"name"`
+	Params SelectRowsParams
+	Body   *SelectRowsJSONRequestBody
+}
+
+type SelectRowsResponseObject interface {
+	VisitSelectRowsResponse(w http.ResponseWriter) error
+}
+
+type SelectRows200JSONResponse Rows
+
+func (response SelectRows200JSONResponse) VisitSelectRowsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type SelectRowsdefaultJSONResponse struct {
+	Body       Error
+	StatusCode int
+}
+
+func (response SelectRowsdefaultJSONResponse) VisitSelectRowsResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.StatusCode)
+
+	return json.NewEncoder(w).Encode(response.Body)
+}
+
+type BeginTransactionRequestObject struct {
+}
+
+type BeginTransactionResponseObject interface {
+	VisitBeginTransactionResponse(w http.ResponseWriter) error
+}
+
+type BeginTransaction200JSONResponse Transaction
+
+func (response BeginTransaction200JSONResponse) VisitBeginTransactionResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type BeginTransactiondefaultJSONResponse struct {
+	Body       Error
+	StatusCode int
+}
+
+func (response BeginTransactiondefaultJSONResponse) VisitBeginTransactionResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.StatusCode)
+
+	return json.NewEncoder(w).Encode(response.Body)
+}
+
+type CommitTransactionRequestObject struct {
+	Id string `json:"id"`
 }
 
-type DeleteDuplicateRowsResponseObject interface {
-	VisitDeleteDuplicateRowsResponse(w http.ResponseWriter) error
+type CommitTransactionResponseObject interface {
+	VisitCommitTransactionResponse(w http.ResponseWriter) error
 }
 
-type DeleteDuplicateRows200JSONResponse Info
+type CommitTransaction200JSONResponse Info
 
-func (response DeleteDuplicateRows200JSONResponse) VisitDeleteDuplicateRowsResponse(w http.ResponseWriter) error {
+func (response CommitTransaction200JSONResponse) VisitCommitTransactionResponse(w http.ResponseWriter) error {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(200)
 
 	return json.NewEncoder(w).Encode(response)
 }
 
-type DeleteDuplicateRowsdefaultJSONResponse struct {
+type CommitTransactiondefaultJSONResponse struct {
 	Body       Error
 	StatusCode int
 }
 
-func (response DeleteDuplicateRowsdefaultJSONResponse) VisitDeleteDuplicateRowsResponse(w http.ResponseWriter) error {
+func (response CommitTransactiondefaultJSONResponse) VisitCommitTransactionResponse(w http.ResponseWriter) error {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(response.StatusCode)
 
 	return json.NewEncoder(w).Encode(response.Body)
 }
 
-type SelectRowsRequestObject struct {
-	Name string `json:"name"`
-	Body *SelectRowsJSONRequestBody
+type RollbackTransactionRequestObject struct {
+	Id string `json:"id"`
 }
 
-type SelectRowsResponseObject interface {
-	VisitSelectRowsResponse(w http.ResponseWriter) error
+type RollbackTransactionResponseObject interface {
+	VisitRollbackTransactionResponse(w http.ResponseWriter) error
 }
 
-type SelectRows200JSONResponse Rows
+type RollbackTransaction200JSONResponse Info
 
-func (response SelectRows200JSONResponse) VisitSelectRowsResponse(w http.ResponseWriter) error {
+func (response RollbackTransaction200JSONResponse) VisitRollbackTransactionResponse(w http.ResponseWriter) error {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(200)
 
 	return json.NewEncoder(w).Encode(response)
 }
 
-type SelectRowsdefaultJSONResponse struct {
+type RollbackTransactiondefaultJSONResponse struct {
 	Body       Error
 	StatusCode int
 }
 
-func (response SelectRowsdefaultJSONResponse) VisitSelectRowsResponse(w http.ResponseWriter) error {
+func (response RollbackTransactiondefaultJSONResponse) VisitRollbackTransactionResponse(w http.ResponseWriter) error {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(response.StatusCode)
 
@@ -521,6 +1497,24 @@ type StrictServerInterface interface {
 	// (GET /.schema)
 	DbSchema(ctx context.Context, request DbSchemaRequestObject) (DbSchemaResponseObject, error)
 
+	// (GET /.sql)
+	ExportSql(ctx context.Context, request ExportSqlRequestObject) (ExportSqlResponseObject, error)
+
+	// (GET /admin/dump)
+	DownloadDump(ctx context.Context, request DownloadDumpRequestObject) (DownloadDumpResponseObject, error)
+
+	// (POST /admin/dump)
+	StoreDump(ctx context.Context, request StoreDumpRequestObject) (StoreDumpResponseObject, error)
+
+	// (PUT /admin/dump)
+	RestoreDump(ctx context.Context, request RestoreDumpRequestObject) (RestoreDumpResponseObject, error)
+
+	// (GET /admin/dump/status)
+	DumpStatus(ctx context.Context, request DumpStatusRequestObject) (DumpStatusResponseObject, error)
+
+	// (POST /batch)
+	ExecuteBatch(ctx context.Context, request ExecuteBatchRequestObject) (ExecuteBatchResponseObject, error)
+
 	// (POST /delete-table/{name}/)
 	DeleteTable(ctx context.Context, request DeleteTableRequestObject) (DeleteTableResponseObject, error)
 
@@ -533,14 +1527,35 @@ type StrictServerInterface interface {
 	// (POST /table/{name})
 	InsertRows(ctx context.Context, request InsertRowsRequestObject) (InsertRowsResponseObject, error)
 
+	// (GET /table/{name}/csv)
+	ExportCsv(ctx context.Context, request ExportCsvRequestObject) (ExportCsvResponseObject, error)
+
+	// (POST /table/{name}/csv)
+	ImportCsv(ctx context.Context, request ImportCsvRequestObject) (ImportCsvResponseObject, error)
+
 	// (POST /table/{name}/delete)
 	DeleteRows(ctx context.Context, request DeleteRowsRequestObject) (DeleteRowsResponseObject, error)
 
+	// (GET /table/{name}/export)
+	ExportRows(ctx context.Context, request ExportRowsRequestObject) (ExportRowsResponseObject, error)
+
+	// (POST /table/{name}/import)
+	ImportRows(ctx context.Context, request ImportRowsRequestObject) (ImportRowsResponseObject, error)
+
 	// (POST /table/{name}/remove-duplicates)
 	DeleteDuplicateRows(ctx context.Context, request DeleteDuplicateRowsRequestObject) (DeleteDuplicateRowsResponseObject, error)
 
 	// (POST /table/{name}/select)
 	SelectRows(ctx context.Context, request SelectRowsRequestObject) (SelectRowsResponseObject, error)
+
+	// (POST /tx)
+	BeginTransaction(ctx context.Context, request BeginTransactionRequestObject) (BeginTransactionResponseObject, error)
+
+	// (POST /tx/{id}/commit)
+	CommitTransaction(ctx context.Context, request CommitTransactionRequestObject) (CommitTransactionResponseObject, error)
+
+	// (POST /tx/{id}/rollback)
+	RollbackTransaction(ctx context.Context, request RollbackTransactionRequestObject) (RollbackTransactionResponseObject, error)
 }
 
 type StrictHandlerFunc func(ctx echo.Context, args interface{}) (interface{}, error)
@@ -579,11 +1594,158 @@ func (sh *strictHandler) DbSchema(ctx echo.Context) error {
 	return nil
 }
 
+// ExportSql operation middleware
+func (sh *strictHandler) ExportSql(ctx echo.Context) error {
+	var request ExportSqlRequestObject
+
+	handler := func(ctx echo.Context, request interface{}) (interface{}, error) {
+		return sh.ssi.ExportSql(ctx.Request().Context(), request.(ExportSqlRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ExportSql")
+	}
+
+	response, err := handler(ctx, request)
+
+	if err != nil {
+		return err
+	} else if validResponse, ok := response.(ExportSqlResponseObject); ok {
+		return validResponse.VisitExportSqlResponse(ctx.Response())
+	} else if response != nil {
+		return fmt.Errorf("Unexpected response type: %T", response)
+	}
+	return nil
+}
+
+// DownloadDump operation middleware
+func (sh *strictHandler) DownloadDump(ctx echo.Context) error {
+	var request DownloadDumpRequestObject
+
+	handler := func(ctx echo.Context, request interface{}) (interface{}, error) {
+		return sh.ssi.DownloadDump(ctx.Request().Context(), request.(DownloadDumpRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "DownloadDump")
+	}
+
+	response, err := handler(ctx, request)
+
+	if err != nil {
+		return err
+	} else if validResponse, ok := response.(DownloadDumpResponseObject); ok {
+		return validResponse.VisitDownloadDumpResponse(ctx.Response())
+	} else if response != nil {
+		return fmt.Errorf("Unexpected response type: %T", response)
+	}
+	return nil
+}
+
+// StoreDump operation middleware
+func (sh *strictHandler) StoreDump(ctx echo.Context) error {
+	var request StoreDumpRequestObject
+
+	handler := func(ctx echo.Context, request interface{}) (interface{}, error) {
+		return sh.ssi.StoreDump(ctx.Request().Context(), request.(StoreDumpRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "StoreDump")
+	}
+
+	response, err := handler(ctx, request)
+
+	if err != nil {
+		return err
+	} else if validResponse, ok := response.(StoreDumpResponseObject); ok {
+		return validResponse.VisitStoreDumpResponse(ctx.Response())
+	} else if response != nil {
+		return fmt.Errorf("Unexpected response type: %T", response)
+	}
+	return nil
+}
+
+// RestoreDump operation middleware
+func (sh *strictHandler) RestoreDump(ctx echo.Context) error {
+	var request RestoreDumpRequestObject
+
+	request.Body = ctx.Request().Body
+
+	handler := func(ctx echo.Context, request interface{}) (interface{}, error) {
+		return sh.ssi.RestoreDump(ctx.Request().Context(), request.(RestoreDumpRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "RestoreDump")
+	}
+
+	response, err := handler(ctx, request)
+
+	if err != nil {
+		return err
+	} else if validResponse, ok := response.(RestoreDumpResponseObject); ok {
+		return validResponse.VisitRestoreDumpResponse(ctx.Response())
+	} else if response != nil {
+		return fmt.Errorf("Unexpected response type: %T", response)
+	}
+	return nil
+}
+
+// DumpStatus operation middleware
+func (sh *strictHandler) DumpStatus(ctx echo.Context) error {
+	var request DumpStatusRequestObject
+
+	handler := func(ctx echo.Context, request interface{}) (interface{}, error) {
+		return sh.ssi.DumpStatus(ctx.Request().Context(), request.(DumpStatusRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "DumpStatus")
+	}
+
+	response, err := handler(ctx, request)
+
+	if err != nil {
+		return err
+	} else if validResponse, ok := response.(DumpStatusResponseObject); ok {
+		return validResponse.VisitDumpStatusResponse(ctx.Response())
+	} else if response != nil {
+		return fmt.Errorf("Unexpected response type: %T", response)
+	}
+	return nil
+}
+
+// ExecuteBatch operation middleware
+func (sh *strictHandler) ExecuteBatch(ctx echo.Context) error {
+	var request ExecuteBatchRequestObject
+
+	var body ExecuteBatchJSONRequestBody
+	if err := ctx.Bind(&body); err != nil {
+		return err
+	}
+	request.Body = &body
+
+	handler := func(ctx echo.Context, request interface{}) (interface{}, error) {
+		return sh.ssi.ExecuteBatch(ctx.Request().Context(), request.(ExecuteBatchRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ExecuteBatch")
+	}
+
+	response, err := handler(ctx, request)
+
+	if err != nil {
+		return err
+	} else if validResponse, ok := response.(ExecuteBatchResponseObject); ok {
+		return validResponse.VisitExecuteBatchResponse(ctx.Response())
+	} else if response != nil {
+		return fmt.Errorf("Unexpected response type: %T", response)
+	}
+	return nil
+}
+
 // DeleteTable operation middleware
-func (sh *strictHandler) DeleteTable(ctx echo.Context, name string) error {
+func (sh *strictHandler) DeleteTable(ctx echo.Context, name string, params DeleteTableParams) error {
 	var request DeleteTableRequestObject
 
 	request.Name = name
+	request.Params = params
 
 	handler := func(ctx echo.Context, request interface{}) (interface{}, error) {
 		return sh.ssi.DeleteTable(ctx.Request().Context(), request.(DeleteTableRequestObject))
@@ -605,9 +1767,11 @@ func (sh *strictHandler) DeleteTable(ctx echo.Context, name string) error {
 }
 
 // CreateTable operation middleware
-func (sh *strictHandler) CreateTable(ctx echo.Context) error {
+func (sh *strictHandler) CreateTable(ctx echo.Context, params CreateTableParams) error {
 	var request CreateTableRequestObject
 
+	request.Params = params
+
 	var body CreateTableJSONRequestBody
 	if err := ctx.Bind(&body); err != nil {
 		return err
@@ -634,10 +1798,11 @@ func (sh *strictHandler) CreateTable(ctx echo.Context) error {
 }
 
 // UpdateRows operation middleware
-func (sh *strictHandler) UpdateRows(ctx echo.Context, name string) error {
+func (sh *strictHandler) UpdateRows(ctx echo.Context, name string, params UpdateRowsParams) error {
 	var request UpdateRowsRequestObject
 
 	request.Name = name
+	request.Params = params
 
 	var body UpdateRowsJSONRequestBody
 	if err := ctx.Bind(&body); err != nil {
@@ -665,10 +1830,11 @@ func (sh *strictHandler) UpdateRows(ctx echo.Context, name string) error {
 }
 
 // InsertRows operation middleware
-func (sh *strictHandler) InsertRows(ctx echo.Context, name string) error {
+func (sh *strictHandler) InsertRows(ctx echo.Context, name string, params InsertRowsParams) error {
 	var request InsertRowsRequestObject
 
 	request.Name = name
+	request.Params = params
 
 	var body InsertRowsJSONRequestBody
 	if err := ctx.Bind(&body); err != nil {
@@ -695,11 +1861,64 @@ func (sh *strictHandler) InsertRows(ctx echo.Context, name string) error {
 	return nil
 }
 
+// ExportCsv operation middleware
+func (sh *strictHandler) ExportCsv(ctx echo.Context, name string) error {
+	var request ExportCsvRequestObject
+
+	request.Name = name
+
+	handler := func(ctx echo.Context, request interface{}) (interface{}, error) {
+		return sh.ssi.ExportCsv(ctx.Request().Context(), request.(ExportCsvRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ExportCsv")
+	}
+
+	response, err := handler(ctx, request)
+
+	if err != nil {
+		return err
+	} else if validResponse, ok := response.(ExportCsvResponseObject); ok {
+		return validResponse.VisitExportCsvResponse(ctx.Response())
+	} else if response != nil {
+		return fmt.Errorf("Unexpected response type: %T", response)
+	}
+	return nil
+}
+
+// ImportCsv operation middleware
+func (sh *strictHandler) ImportCsv(ctx echo.Context, name string) error {
+	var request ImportCsvRequestObject
+
+	request.Name = name
+
+	request.Body = ctx.Request().Body
+
+	handler := func(ctx echo.Context, request interface{}) (interface{}, error) {
+		return sh.ssi.ImportCsv(ctx.Request().Context(), request.(ImportCsvRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ImportCsv")
+	}
+
+	response, err := handler(ctx, request)
+
+	if err != nil {
+		return err
+	} else if validResponse, ok := response.(ImportCsvResponseObject); ok {
+		return validResponse.VisitImportCsvResponse(ctx.Response())
+	} else if response != nil {
+		return fmt.Errorf("Unexpected response type: %T", response)
+	}
+	return nil
+}
+
 // DeleteRows operation middleware
-func (sh *strictHandler) DeleteRows(ctx echo.Context, name string) error {
+func (sh *strictHandler) DeleteRows(ctx echo.Context, name string, params DeleteRowsParams) error {
 	var request DeleteRowsRequestObject
 
 	request.Name = name
+	request.Params = params
 
 	var body DeleteRowsJSONRequestBody
 	if err := ctx.Bind(&body); err != nil {
@@ -726,11 +1945,64 @@ func (sh *strictHandler) DeleteRows(ctx echo.Context, name string) error {
 	return nil
 }
 
+// ExportRows operation middleware
+func (sh *strictHandler) ExportRows(ctx echo.Context, name string) error {
+	var request ExportRowsRequestObject
+
+	request.Name = name
+
+	handler := func(ctx echo.Context, request interface{}) (interface{}, error) {
+		return sh.ssi.ExportRows(ctx.Request().Context(), request.(ExportRowsRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ExportRows")
+	}
+
+	response, err := handler(ctx, request)
+
+	if err != nil {
+		return err
+	} else if validResponse, ok := response.(ExportRowsResponseObject); ok {
+		return validResponse.VisitExportRowsResponse(ctx.Response())
+	} else if response != nil {
+		return fmt.Errorf("Unexpected response type: %T", response)
+	}
+	return nil
+}
+
+// ImportRows operation middleware
+func (sh *strictHandler) ImportRows(ctx echo.Context, name string) error {
+	var request ImportRowsRequestObject
+
+	request.Name = name
+
+	request.Body = ctx.Request().Body
+
+	handler := func(ctx echo.Context, request interface{}) (interface{}, error) {
+		return sh.ssi.ImportRows(ctx.Request().Context(), request.(ImportRowsRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ImportRows")
+	}
+
+	response, err := handler(ctx, request)
+
+	if err != nil {
+		return err
+	} else if validResponse, ok := response.(ImportRowsResponseObject); ok {
+		return validResponse.VisitImportRowsResponse(ctx.Response())
+	} else if response != nil {
+		return fmt.Errorf("Unexpected response type: %T", response)
+	}
+	return nil
+}
+
 // DeleteDuplicateRows operation middleware
-func (sh *strictHandler) DeleteDuplicateRows(ctx echo.Context, name string) error {
+func (sh *strictHandler) DeleteDuplicateRows(ctx echo.Context, name string, params DeleteDuplicateRowsParams) error {
 	var request DeleteDuplicateRowsRequestObject
 
 	request.Name = name
+	request.Params = params
 
 	handler := func(ctx echo.Context, request interface{}) (interface{}, error) {
 		return sh.ssi.DeleteDuplicateRows(ctx.Request().Context(), request.(DeleteDuplicateRowsRequestObject))
@@ -752,10 +2024,11 @@ func (sh *strictHandler) DeleteDuplicateRows(ctx echo.Context, name string) erro
 }
 
 // SelectRows operation middleware
-func (sh *strictHandler) SelectRows(ctx echo.Context, name string) error {
+func (sh *strictHandler) SelectRows(ctx echo.Context, name string, params SelectRowsParams) error {
 	var request SelectRowsRequestObject
 
 	request.Name = name
+	request.Params = params
 
 	var body SelectRowsJSONRequestBody
 	if err := ctx.Bind(&body); err != nil {
@@ -782,25 +2055,126 @@ func (sh *strictHandler) SelectRows(ctx echo.Context, name string) error {
 	return nil
 }
 
+// BeginTransaction operation middleware
+func (sh *strictHandler) BeginTransaction(ctx echo.Context) error {
+	var request BeginTransactionRequestObject
+
+	handler := func(ctx echo.Context, request interface{}) (interface{}, error) {
+		return sh.ssi.BeginTransaction(ctx.Request().Context(), request.(BeginTransactionRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "BeginTransaction")
+	}
+
+	response, err := handler(ctx, request)
+
+	if err != nil {
+		return err
+	} else if validResponse, ok := response.(BeginTransactionResponseObject); ok {
+		return validResponse.VisitBeginTransactionResponse(ctx.Response())
+	} else if response != nil {
+		return fmt.Errorf("Unexpected response type: %T", response)
+	}
+	return nil
+}
+
+// CommitTransaction operation middleware
+func (sh *strictHandler) CommitTransaction(ctx echo.Context, id string) error {
+	var request CommitTransactionRequestObject
+
+	request.Id = id
+
+	handler := func(ctx echo.Context, request interface{}) (interface{}, error) {
+		return sh.ssi.CommitTransaction(ctx.Request().Context(), request.(CommitTransactionRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "CommitTransaction")
+	}
+
+	response, err := handler(ctx, request)
+
+	if err != nil {
+		return err
+	} else if validResponse, ok := response.(CommitTransactionResponseObject); ok {
+		return validResponse.VisitCommitTransactionResponse(ctx.Response())
+	} else if response != nil {
+		return fmt.Errorf("Unexpected response type: %T", response)
+	}
+	return nil
+}
+
+// RollbackTransaction operation middleware
+func (sh *strictHandler) RollbackTransaction(ctx echo.Context, id string) error {
+	var request RollbackTransactionRequestObject
+
+	request.Id = id
+
+	handler := func(ctx echo.Context, request interface{}) (interface{}, error) {
+		return sh.ssi.RollbackTransaction(ctx.Request().Context(), request.(RollbackTransactionRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "RollbackTransaction")
+	}
+
+	response, err := handler(ctx, request)
+
+	if err != nil {
+		return err
+	} else if validResponse, ok := response.(RollbackTransactionResponseObject); ok {
+		return validResponse.VisitRollbackTransactionResponse(ctx.Response())
+	} else if response != nil {
+		return fmt.Errorf("Unexpected response type: %T", response)
+	}
+	return nil
+}
+
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/+xYS2/jNhD+K8K0R8Vy28tCpz7Sgw8tiiQ9LYKCosYSdyVSHVIJDEP/veBQsvyQ3GyR",
-	"rb3B3ixxXvy+bzQ0tyBN3RiN2llIt2BlibXgn7fZPT/438phzS+/JVxDCt8ko1vS+yQPIquw9+licJsG",
-	"IQVBJDb++VciQz5GQ6ZBcgo5Yo3WigL9z97DOlK6gK6LgfDvVhHmkL7fGT7uQpvsA0rnY6/02nym0Hfm",
-	"2QcQea6cMlpUf+zlcNTitM/vog5F7KA7KuEUoDvzbF8Mti9rIsZI2SEU0lRtrc+UsQXUbe3BUNphgQQe",
-	"IlFBDLIU/qn3CK9X+gliUPUhajPw9sl7symQ77FC6X42+Wau9JcAEjDvYpBGB7ZeBuNkuRYO4kxVvS/4",
-	"k7LtpzXPfN84n8VvbVrHJ1X92eTC4RyWnwRMDLlw4r9huEvUBzkF0PuovnGl0U5Ixzqshar8HpFKpf6y",
-	"GzJO/PhRt4tWQAyakYB7Xo3ueRViaMn7lM41Nk2SQrmyzRbS1IkNAXgzaCWpxpcFKfwUrckUN3kWEVoX",
-	"ednTWkiMCtRIwmEeZZvIiEbdSJNjgV7BlZKoLVPRF/Lb6oFZUq7yj9MxIYYnJBsSf7dYLpbexzSoRaMg",
-	"hR/4VQyNcCVTkyxG/RTIuBxWf4euJW0jj20mLEa9PUcl4a1WOaTjV9zzYxujbVDC98vlgDtqji+aplKS",
-	"PZMP1uhDEZ8TwC4HU3pYZ7CJhtyBhrVoK/dq6cNgmciNw0IXQ5JjhQ5vuJuSrSevS7hBjJ2A95atozyL",
-	"2OEUVl5/6NcaQaJGh2QhfX8cigNErBavd0iZ5VHJ/crYPWGojHs/7vnHz0glD9IJKAN6V0BjoGOWN0ko",
-	"zvH2C68PvHnM0e7mzqts4+AUdLqZoAY79uQ+690FmO0RuxJm+85kgoWT5SnDLY+3iMyzjZSeoTnMQD5Q",
-	"XaQ7X19We1N9ikQ+sjAoVyGqgaSLiiqe+UYobZFcUJAzMwpasdFbUhDv5QvQzlV+kPrjw/zgGQakF9Wa",
-	"TB1k5U+Qu6PwzCHijWnsS5DYdZ1mBokR1uYJb/I25AxwnFXbzvRYdzNSux3sL6e5r0xjl1i+7pinN6z/",
-	"K6nh1uQtfT/27oGm/ksGWDK//H9+RuYm50DTZcXVxWCRngbix4uQNEkqI0VVGuvSd8t3S+ji7dFFyZpM",
-	"kWeLEsl8bEXTLKSpoXvs/gkAAP//fD2g+xgWAAA=",
+	"H4sIAAAAAAAC/+xb32/jNvL/Vwb87qNiZ7f9Hoo83XV3H4K7aw/J3uGAzaKgxbHNjUQqJGXHDfy/H4ak",
+	"ftiiEqdN4hTbhyCSSQ6Hnxl+hkNSdyzXZaUVKmfZ2R2ruOElOjT+7eNthblD8R80VmpFPwm0uZGV869s",
+	"FQoycEvuIF9ytUABRq8tlLV1sOQrzCDXZcmVgDmXhYW1dEuwjrvawvdv34F2SzRraZFlTJLQmxrNhmVM",
+	"8RK7PljGbL7EkpMWc21K7tgZk8r95XuWMbepMLziAg3bbjP2yXBleU6anouh6lKAnoPrKoHTgLeY1w5b",
+	"jUlXqboRSNtUEcALrRDkHHQpnUPRqL9ELtB0+v/3pKfJybnYGUjU2zoj1YJtt9um0OP/I3f58kctNvRS",
+	"GV2hcRJ9UdTIP0uHpX94Y3DOztj/TTujTqO46fvQgG1bsLgxfOOhMnhTS4OCnX3uBH9pK+rZV8wdtWyE",
+	"DNBsANJzmJHSGcwlFsJCbVGAwAqpUIGXmA3GUtSlenAEF3r9Ey/RkiK5VkJS3weMu625zZjgjh/QD1VF",
+	"tZAKh0O1Thu+QAjlNOLcICePcHxWYAYlltpsYLYBgXNeF46RsLokdEMZy5iQ9roHceMAGaPJc4CGfjSd",
+	"H+1bwwM60I1lh/nKpf8/dJWMBTFDx21q3rUjDR1/it0Ko6vmWSqLhkCxWJBfZayuBHe+GhboHwyWeoUf",
+	"6qqQOXdok1CtDqMlT0cNN802EHoDbSB016cq77wHENUhBNSfVdHvA3z3zKwLtOQwg+leorV8kUb+cI/Z",
+	"JjvuzyQuwgsv/tXr35kasz2MSWIEuPSg4QrNBtqJOYH33guh5JUlbl3xosbYIo9FBPwMAW9qXoDTGRnF",
+	"aQjakfuSDtxpYwFvMlCYwcLRH2ZQOPrDDIiglXbnCoiBZujWiCpYzzdXwmaAk8UE7q5YgYtfClQLt7xi",
+	"Z/TDwtHDd5P/32ZwxaSIP0tFD5/fZvDuiy9Zo1wsXSyNnfSqbCfwc+yM9E7rxA1CIa2zsbIf2P6ABi2U",
+	"dmDrqtLGBQc2yItztfIVZUlcFCf8BP6OGwtvuNr8PPfFb3hR/DwH7xGWRCnAsnKboAZpqtCS2I5QM+AO",
+	"CuTWAQU4bYAXBdV0SyyDycpA8de4gTek3VIT1e9LaryDWijdtmosEbT0EN5dMVthLtHS6xVzBvGKbTOq",
+	"9rUuqx1TvRuao/APb7fbL9vJlWIJL/8wu2y58iAK9GQ1zoMf6rK69NQwnKvEHrUJz7tzhkooHNZlZcFK",
+	"lSPxi3GHEErGCm4d9TuU62SJ0UJQEF06sE6b2FVfOjHfCVVnCT6lDj4ao02SZtrST9T87O5AoVb+mgqi",
+	"8td7NAapYLZxaA8DxrOq7Sk9xsKtZVIM3I78UObdE95UTMk+L2n2jpE7Us8Jdwm/NzDNpSGUrmVVoYBC",
+	"Kg/PQc78D6kwDC/hyiEco0jhl7HY3wHgtnK6RlkztCQmaq6fCe5uvAP5RVzTDUd6cM9eRHavArSEfCCa",
+	"ptqE5W2fokaWWZ31LvR6t8UBK9t9GR01ptblB632GhwJKV6wjOVLTm+xRfj5XK3IY8td1EZQjp3HaimQ",
+	"L/3ycSw9eqGUIqm1ZTviUsr348uQEA5OPZ4k5bCPC40PZAc/8XJkEg1B6BLjIQhSPDwVpUiC+2+/wh/z",
+	"jGfOHAce0UqJQoYabz0NBzrMtXI891ECSy4LGjyapZS/2I3Rjv/1WtWTmnf7C5e+FC59KSVThtosnavs",
+	"2XS6kG5Zzya5Lqc2CPCD2XGrv8Hc6MWJmIGhQExz2cx5jrBAhYbHVafmlTzJtcAFKpaxQuaorLd0VOSf",
+	"55+8E0hX0GtaJuulbezt5HRySm10hYpXkp2x7/xPGau4W3oLTSedey7QDSfFBbraKAuE7YxbhFjfSzW8",
+	"2f/ploBkH1tpZYNDvDs9bXBH5eXzKqSdUqvpVxt8s1PiPgdo+/Am3Zu8vgSavoMZwmR9qu5jjB/2jU3B",
+	"NiNAb4oH0bQ3BYTfm5TN5/PWJwNhwRXyDx/1La1QpPGp9gD4j7eVNu7ypngc8lHLxKbfTCruGS2xe7aH",
+	"eTuK48HNRSnVVMSFexL0S2eQl5DXxqBynSc3a2FSC+LwB26t16rQXHwIK/1HAKxzh+7E+r5/N9JeRa+v",
+	"nvd85DiwZ6zSNomzNg2qZYlCcofFZgCpr/Z4PB83Cr/8Tbms1/G4LJGxqk5yQ1Cu9c+50SXUFblfzNyy",
+	"1okbjvCDqQqeoxgAHQW2UN/UaNsF3Yt5rTZgFa/sUjsQnSohgNNifXsENzD4Ghxhl7+mtt3xuDd2FHwv",
+	"necqPsR0cMBh3W7KcwbnrpfEmL1+cYBHw9pvP5OoNH993D2eshQbtBFogFvgsF5qSgZQuiXu7trRLPS6",
+	"oyB3V1rhwAhRuD9zOng2Pg6E7jwrAUQzqCeffo85HotbNMNDstQErYuwhTowB7X//u2753cef7QhtN9e",
+	"pQMMwHhiC80i+2iuHM5WTnwYmN5RirCdjnv2B18bxKw9qNpjCF/enCD1T6k/74vyAoA6bE5kKZfo8qVY",
+	"sutj953JZmk4OiWmu0fN2y9HiBfxKOv44aI9IUwbOqQR44Z+v3NsuGfo32CGpyexnZOBIRjB/WyXah57",
+	"JRERfyWeEakg3DWJwW63TTye9dQm1YibhB2ei5BuvnI6eLjB/j2b53Ld3r5YMgD7Q9mYwx/fcRtH6Dnu",
+	"txxUx5LasAMT5ovTI/Pl3Ff6Y8yXZ3L+eAXi1bv9q+TraW5Xo3kf+g2+4HnBD7mF3K7CHYxwHy6skz3S",
+	"yp+4pHcJ39vVizvow8s1h7euQeB37TcQKHreQ+qVUYk/pn7IdC3LZCDVihcynkb7LLM7+t0joPKo5h2j",
+	"kye17MtTSP9aQUKpaE/TJrWvgEjiFb/RBKFJZIhG/P5icIhZ72rZSHb452rwccbbOcZOhMWmlOZ/sIp4",
+	"PVEyke9+6zsuO9MsxOTRkB32z/dCtsI1ETkZW5aSxvTVJmZbiNRHmW2P21m5PVFiCPRvIHej1/FOqs38",
+	"pcgKjY95r8TYgefHOTVaO23f3cwhC68UypsLXf42HuXq7ZHv44L+8TzlEM58ASf5c0lwv/uGy/4norvt",
+	"/9DqoK26v04YWRq0HxL8gTLgb3oDuXGN+InIOLP58n0vyJpgTEsXXyYthKooYL1EBW+aGjGzkRYiXyR4",
+	"LNw0/KZ3T3qXLRMGjGaYUfFLst3Ynk7jFsd35ttx3/0RF1L1v0DMQDoL0n9mWHFrQ+jd/36wycqHnyva",
+	"+L0iyOE1Id9ZT9BzHrT3u0kA5PrFxzPM9E6KLQko5T0E896X71opfEtm978f7R+ycwda5YnjLS9u1w73",
+	"E0qvAynSpCLFYNI93SL6iTY1A4yvYUIGuxtdFDOeX49b/iLWONj2QtqcG5G65xRFfXt2b2A+tuW3GbNo",
+	"Vg3W3R3ps+m00Dkvltq6sx9Ofzj1OzS7d6jpJrOYTZZo9HXNq4puU7Ptl+3/BgCWSl4hPT8AAA==",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
@@ -0,0 +1,174 @@
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ssyrota/frog-db/src/core/db"
+	"github.com/ssyrota/frog-db/src/core/db/table"
+	errs "github.com/ssyrota/frog-db/src/core/err"
+	"github.com/ssyrota/frog-db/src/web/server"
+)
+
+// Transactions, that are not used for this time, are rolled back
+const txIdleTimeout = 10 * time.Minute
+
+// Interval, that idle transactions are looked for with
+const txSweepInterval = time.Minute
+
+// Name of job, that rolls back idle transactions
+const txSweepJob = "rollback idle transactions"
+
+// Open transactions by id
+type transactions struct {
+	mu  sync.Mutex
+	txs map[string]*openTx
+}
+
+type openTx struct {
+	tx       *db.Tx
+	lastUsed time.Time
+}
+
+func newTransactions() *transactions {
+	return &transactions{txs: make(map[string]*openTx)}
+}
+
+func (open *openTx) idle(now time.Time) bool {
+	return now.Sub(open.lastUsed) > txIdleTimeout
+}
+
+// Register transaction
+func (t *transactions) add(tx *db.Tx) (string, error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(idBytes)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.txs[id] = &openTx{tx, time.Now()}
+	return id, nil
+}
+
+// Get transaction, idle one is rolled back and is not found then
+func (t *transactions) get(id string) (*db.Tx, error) {
+	t.mu.Lock()
+	open, ok := t.txs[id]
+	if !ok {
+		t.mu.Unlock()
+		return nil, errs.NewErrTxNotFound(id)
+	}
+	now := time.Now()
+	if open.idle(now) {
+		delete(t.txs, id)
+		t.mu.Unlock()
+		rollbackIdle(id, open.tx)
+		return nil, errs.NewErrTxNotFound(id)
+	}
+	open.lastUsed = now
+	t.mu.Unlock()
+	return open.tx, nil
+}
+
+// Unregister transaction, that is finished. Idle one is rolled back
+// and is not found then.
+func (t *transactions) remove(id string) (*db.Tx, error) {
+	t.mu.Lock()
+	open, ok := t.txs[id]
+	if !ok {
+		t.mu.Unlock()
+		return nil, errs.NewErrTxNotFound(id)
+	}
+	delete(t.txs, id)
+	t.mu.Unlock()
+	if open.idle(time.Now()) {
+		rollbackIdle(id, open.tx)
+		return nil, errs.NewErrTxNotFound(id)
+	}
+	return open.tx, nil
+}
+
+// Roll back idle transactions, it's run by supervisor every txSweepInterval.
+// Transactions are rolled back outside of lock, as they may wait for
+// commands, that are being executed.
+func (t *transactions) sweep(ctx context.Context) error {
+	now := time.Now()
+	idle := make(map[string]*db.Tx)
+	t.mu.Lock()
+	for id, open := range t.txs {
+		if open.idle(now) {
+			delete(t.txs, id)
+			idle[id] = open.tx
+		}
+	}
+	t.mu.Unlock()
+	for id, tx := range idle {
+		rollbackIdle(id, tx)
+	}
+	return nil
+}
+
+func rollbackIdle(id string, tx *db.Tx) {
+	if err := tx.Rollback(); err != nil {
+		log.Printf("rollback idle transaction %s: %s", id, err.Error())
+	}
+}
+
+// Execute command within transaction, if its id is set
+func (h *handler) execute(ctx context.Context, txID *server.TransactionId, command any) (*[]table.ColumnSet, error) {
+	ctx, cancel := h.withTimeout(ctx)
+	defer cancel()
+	if txID == nil {
+		return h.db.ExecuteContext(ctx, command)
+	}
+	tx, err := h.txs.get(*txID)
+	if err != nil {
+		return nil, err
+	}
+	return tx.ExecuteContext(ctx, command)
+}
+
+// BeginTransaction implementation.
+func (h *handler) BeginTransaction(ctx context.Context, request server.BeginTransactionRequestObject) (server.BeginTransactionResponseObject, error) {
+	id, err := h.txs.add(h.db.Begin())
+	if err != nil {
+		return server.BeginTransactiondefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: http.StatusInternalServerError}, nil
+	}
+	return server.BeginTransaction200JSONResponse{Id: id}, nil
+}
+
+// CommitTransaction implementation.
+func (h *handler) CommitTransaction(ctx context.Context, request server.CommitTransactionRequestObject) (server.CommitTransactionResponseObject, error) {
+	tx, err := h.txs.remove(request.Id)
+	if err != nil {
+		return server.CommitTransactiondefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: http.StatusNotFound}, nil
+	}
+	if err := tx.Commit(); err != nil {
+		status := http.StatusInternalServerError
+		var conflict *errs.ErrTxConflict
+		if errors.As(err, &conflict) {
+			status = http.StatusConflict
+		}
+		return server.CommitTransactiondefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: status}, nil
+	}
+	return server.CommitTransaction200JSONResponse{Message: "transaction committed"}, nil
+}
+
+// RollbackTransaction implementation.
+func (h *handler) RollbackTransaction(ctx context.Context, request server.RollbackTransactionRequestObject) (server.RollbackTransactionResponseObject, error) {
+	tx, err := h.txs.remove(request.Id)
+	if err != nil {
+		return server.RollbackTransactiondefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: http.StatusNotFound}, nil
+	}
+	if err := tx.Rollback(); err != nil {
+		return server.RollbackTransactiondefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: http.StatusConflict}, nil
+	}
+	return server.RollbackTransaction200JSONResponse{Message: "transaction rolled back"}, nil
+}
@@ -2,33 +2,72 @@ package web
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"time"
 
+	"github.com/dustin/go-humanize/english"
 	"github.com/labstack/echo/v4"
 	echo_middleware "github.com/labstack/echo/v4/middleware"
 	"github.com/ssyrota/frog-db/src/core/db"
 	"github.com/ssyrota/frog-db/src/core/db/dbtypes"
 	"github.com/ssyrota/frog-db/src/core/db/schema"
+	"github.com/ssyrota/frog-db/src/core/db/supervisor"
 	"github.com/ssyrota/frog-db/src/core/db/table"
+	errs "github.com/ssyrota/frog-db/src/core/err"
 	"github.com/ssyrota/frog-db/src/web/server"
 )
 
-func New(db *db.Database, port uint16) *WebServer {
-	return &WebServer{port, db}
+func New(db *db.Database, port uint16, opts ...Option) *WebServer {
+	s := &WebServer{port: port, db: db, echo: echo.New()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 type WebServer struct {
 	port uint16
 	db   *db.Database
+	echo *echo.Echo
+	// Deadline of commands and dumps of request, zero means no deadline
+	requestTimeout time.Duration
+}
+
+type Option func(s *WebServer)
+
+// Set time, that commands and stored dumps of request may take, they fail
+// with ErrTimeout after it. There is no deadline by default.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(s *WebServer) {
+		s.requestTimeout = timeout
+	}
+}
+
+// Stop accepting connections and wait for in-flight requests until ctx is
+// done, requests still running then are dropped. Run returns nil after it.
+func (s *WebServer) Shutdown(ctx context.Context) error {
+	if err := s.echo.Shutdown(ctx); err != nil {
+		s.echo.Close()
+		return err
+	}
+	return nil
 }
 
 func (s *WebServer) Run() error {
-	r := echo.New()
+	r := s.echo
+	r.Binder = new(bodyBinder)
 	r.Use(echo_middleware.Logger(), echo_middleware.Recover(), echo_middleware.CORS())
+	txs := newTransactions()
+	jobs := supervisor.New(context.Background())
+	defer jobs.Stop()
+	jobs.Every(txSweepJob, txSweepInterval, txs.sweep)
 	server.RegisterHandlers(
 		r.Group(""),
-		server.NewStrictHandler(&handler{s.db}, []server.StrictMiddlewareFunc{}))
+		server.NewStrictHandler(&handler{s.db, txs, s.requestTimeout}, []server.StrictMiddlewareFunc{}))
 
 	swagger, err := server.GetSwagger()
 	if err != nil {
@@ -38,11 +77,50 @@ func (s *WebServer) Run() error {
 	if err != nil {
 		return fmt.Errorf("swagger register: %w", err)
 	}
-	return r.Start(fmt.Sprintf(":%d", s.port))
+	err = r.Start(fmt.Sprintf(":%d", s.port))
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Echo binds path parameters to request body too, that panics for bodies,
+// that are maps. Parameters are bound by generated server, so only body
+// is bound.
+type bodyBinder struct {
+	echo.DefaultBinder
+}
+
+func (b *bodyBinder) Bind(i interface{}, c echo.Context) error {
+	return b.BindBody(c, i)
 }
 
 type handler struct {
-	db *db.Database
+	db      *db.Database
+	txs     *transactions
+	timeout time.Duration
+}
+
+// Context of request with configured deadline
+func (h *handler) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if h.timeout == 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, h.timeout)
+}
+
+// Status of failed command, timed out commands and rows of unexpected
+// version are not conflicts
+func commandErrorStatus(err error) int {
+	var timeout *errs.ErrTimeout
+	if errors.As(err, &timeout) {
+		return http.StatusGatewayTimeout
+	}
+	var versionConflict *errs.ErrVersionConflict
+	if errors.As(err, &versionConflict) {
+		return http.StatusPreconditionFailed
+	}
+	return http.StatusConflict
 }
 
 // Verify handler implements server.StrictServerInterface
@@ -54,9 +132,13 @@ func (h *handler) CreateTable(ctx context.Context, request server.CreateTableReq
 	for _, s := range *request.Body.Schema {
 		tableSchema[s.Column] = dbtypes.Type(s.Type)
 	}
-	res, err := h.db.Execute(&db.CommandCreateTable{Name: *request.Body.TableName, Schema: tableSchema})
+	command := &db.CommandCreateTable{Name: *request.Body.TableName, Schema: tableSchema}
+	if request.Body.Engine != nil {
+		command.Engine = table.EngineKind(*request.Body.Engine)
+	}
+	res, err := h.execute(ctx, request.Params.XTransactionId, command)
 	if err != nil {
-		return server.CreateTabledefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: http.StatusConflict}, nil
+		return server.CreateTabledefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: commandErrorStatus(err)}, nil
 	}
 	message, ok := (*res)[0]["message"].(string)
 	if !ok {
@@ -68,9 +150,9 @@ func (h *handler) CreateTable(ctx context.Context, request server.CreateTableReq
 
 // DeleteTable implementation.
 func (h *handler) DeleteTable(ctx context.Context, request server.DeleteTableRequestObject) (server.DeleteTableResponseObject, error) {
-	res, err := h.db.Execute(&db.CommandDropTable{Name: request.Name})
+	res, err := h.execute(ctx, request.Params.XTransactionId, &db.CommandDropTable{Name: request.Name})
 	if err != nil {
-		return server.DeleteTabledefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: http.StatusConflict}, nil
+		return server.DeleteTabledefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: commandErrorStatus(err)}, nil
 	}
 	message, ok := (*res)[0]["message"].(string)
 	if !ok {
@@ -82,9 +164,9 @@ func (h *handler) DeleteTable(ctx context.Context, request server.DeleteTableReq
 
 // DeleteDuplicateRows implementation.
 func (h *handler) DeleteDuplicateRows(ctx context.Context, request server.DeleteDuplicateRowsRequestObject) (server.DeleteDuplicateRowsResponseObject, error) {
-	res, err := h.db.Execute(&db.CommandRemoveDuplicates{From: request.Name})
+	res, err := h.execute(ctx, request.Params.XTransactionId, &db.CommandRemoveDuplicates{From: request.Name})
 	if err != nil {
-		return server.DeleteDuplicateRowsdefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: http.StatusConflict}, nil
+		return server.DeleteDuplicateRowsdefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: commandErrorStatus(err)}, nil
 	}
 	message, ok := (*res)[0]["message"].(string)
 	if !ok {
@@ -96,9 +178,9 @@ func (h *handler) DeleteDuplicateRows(ctx context.Context, request server.Delete
 
 // DeleteRows implementation.
 func (h *handler) DeleteRows(ctx context.Context, request server.DeleteRowsRequestObject) (server.DeleteRowsResponseObject, error) {
-	res, err := h.db.Execute(&db.CommandDelete{From: request.Name, Conditions: RowToColumnSet(*request.Body)})
+	res, err := h.execute(ctx, request.Params.XTransactionId, &db.CommandDelete{From: request.Name, Conditions: ConditionsToColumnSet(*request.Body), ExpectedVersion: request.Params.Version})
 	if err != nil {
-		return server.DeleteRowsdefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: http.StatusConflict}, nil
+		return server.DeleteRowsdefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: commandErrorStatus(err)}, nil
 	}
 	message, ok := (*res)[0]["message"].(string)
 	if !ok {
@@ -114,9 +196,9 @@ func (h *handler) InsertRows(ctx context.Context, request server.InsertRowsReque
 	for i, v := range *request.Body {
 		data[i] = RowToColumnSet(v)
 	}
-	res, err := h.db.Execute(&db.CommandInsert{To: request.Name, Data: &data})
+	res, err := h.execute(ctx, request.Params.XTransactionId, &db.CommandInsert{To: request.Name, Data: &data})
 	if err != nil {
-		return server.InsertRowsdefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: http.StatusConflict}, nil
+		return server.InsertRowsdefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: commandErrorStatus(err)}, nil
 	}
 	message, ok := (*res)[0]["message"].(string)
 	if !ok {
@@ -128,10 +210,10 @@ func (h *handler) InsertRows(ctx context.Context, request server.InsertRowsReque
 // SelectRows implementation.
 func (h *handler) SelectRows(ctx context.Context, request server.SelectRowsRequestObject) (server.SelectRowsResponseObject, error) {
 	columns := request.Body.Columns
-	conditions := RowToColumnSet(request.Body.Conditions)
-	res, err := h.db.Execute(&db.CommandSelect{From: request.Name, Conditions: conditions, Fields: &columns})
+	conditions := ConditionsToColumnSet(request.Body.Conditions)
+	res, err := h.execute(ctx, request.Params.XTransactionId, &db.CommandSelect{From: request.Name, Conditions: conditions, Fields: &columns})
 	if err != nil {
-		return server.SelectRowsdefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: http.StatusConflict}, nil
+		return server.SelectRowsdefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: commandErrorStatus(err)}, nil
 	}
 	response := make(server.SelectRows200JSONResponse, len(*res))
 	for i, val := range *res {
@@ -142,11 +224,11 @@ func (h *handler) SelectRows(ctx context.Context, request server.SelectRowsReque
 
 // UpdateRows implementation.
 func (h *handler) UpdateRows(ctx context.Context, request server.UpdateRowsRequestObject) (server.UpdateRowsResponseObject, error) {
-	conditions := RowToColumnSet(request.Body.Conditions)
+	conditions := ConditionsToColumnSet(request.Body.Conditions)
 	data := RowToColumnSet(request.Body.Data)
-	res, err := h.db.Execute(&db.CommandUpdate{TableName: request.Name, Conditions: conditions, Data: data})
+	res, err := h.execute(ctx, request.Params.XTransactionId, &db.CommandUpdate{TableName: request.Name, Conditions: conditions, Data: data, ExpectedVersion: request.Params.Version})
 	if err != nil {
-		return server.UpdateRowsdefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: http.StatusConflict}, nil
+		return server.UpdateRowsdefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: commandErrorStatus(err)}, nil
 	}
 	message, ok := (*res)[0]["message"].(string)
 	if !ok {
@@ -155,6 +237,182 @@ func (h *handler) UpdateRows(ctx context.Context, request server.UpdateRowsReque
 	return server.UpdateRows200JSONResponse{Message: message}, nil
 }
 
+// ExportCsv implementation.
+// Csv is streamed to client while it is written.
+func (h *handler) ExportCsv(ctx context.Context, request server.ExportCsvRequestObject) (server.ExportCsvResponseObject, error) {
+	if err := h.checkTable(request.Name); err != nil {
+		return server.ExportCsvdefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: http.StatusConflict}, nil
+	}
+	return server.ExportCsv200TextcsvResponse{Body: stream(func(w io.Writer) error {
+		return h.db.ExportCsv(request.Name, w)
+	})}, nil
+}
+
+// ImportCsv implementation.
+func (h *handler) ImportCsv(ctx context.Context, request server.ImportCsvRequestObject) (server.ImportCsvResponseObject, error) {
+	res, err := h.db.ImportCsv(request.Name, request.Body)
+	if err != nil {
+		return server.ImportCsvdefaultJSONResponse{Body: server.Error{Message: importErrorMessage(res, err)}, StatusCode: http.StatusConflict}, nil
+	}
+	return server.ImportCsv200JSONResponse(toImportResult(res)), nil
+}
+
+// ExportRows implementation.
+// Rows are streamed to client while they are written.
+func (h *handler) ExportRows(ctx context.Context, request server.ExportRowsRequestObject) (server.ExportRowsResponseObject, error) {
+	if err := h.checkTable(request.Name); err != nil {
+		return server.ExportRowsdefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: http.StatusConflict}, nil
+	}
+	return server.ExportRows200ApplicationxNdjsonResponse{Body: stream(func(w io.Writer) error {
+		return h.db.ExportNdjson(request.Name, w)
+	})}, nil
+}
+
+// ImportRows implementation.
+func (h *handler) ImportRows(ctx context.Context, request server.ImportRowsRequestObject) (server.ImportRowsResponseObject, error) {
+	res, err := h.db.ImportNdjson(request.Name, request.Body)
+	if err != nil {
+		return server.ImportRowsdefaultJSONResponse{Body: server.Error{Message: importErrorMessage(res, err)}, StatusCode: http.StatusConflict}, nil
+	}
+	return server.ImportRows200JSONResponse(toImportResult(res)), nil
+}
+
+// ExportSql implementation.
+// Script is streamed to client while it is written.
+func (h *handler) ExportSql(ctx context.Context, request server.ExportSqlRequestObject) (server.ExportSqlResponseObject, error) {
+	return server.ExportSql200ApplicationsqlResponse{Body: stream(h.db.ExportSql)}, nil
+}
+
+// DownloadDump implementation.
+// Dump is streamed to client while it is written, it has no deadline, but
+// stops once client is gone.
+func (h *handler) DownloadDump(ctx context.Context, request server.DownloadDumpRequestObject) (server.DownloadDumpResponseObject, error) {
+	return server.DownloadDump200ApplicationoctetStreamResponse{Body: stream(func(w io.Writer) error {
+		var writeErr error
+		// Channel is drained even if client is gone, so dump goroutine ends
+		for msg := range h.db.JsonDumpContext(ctx) {
+			if writeErr != nil {
+				continue
+			}
+			if msg.Err != nil {
+				writeErr = msg.Err
+				continue
+			}
+			_, writeErr = w.Write(msg.Payload)
+		}
+		return writeErr
+	})}, nil
+}
+
+// StoreDump implementation.
+func (h *handler) StoreDump(ctx context.Context, request server.StoreDumpRequestObject) (server.StoreDumpResponseObject, error) {
+	ctx, cancel := h.withTimeout(ctx)
+	defer cancel()
+	if err := h.db.StoreDumpContext(ctx); err != nil {
+		status := http.StatusInternalServerError
+		var timeout *errs.ErrTimeout
+		if errors.As(err, &timeout) {
+			status = http.StatusGatewayTimeout
+		}
+		return server.StoreDumpdefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: status}, nil
+	}
+	return server.StoreDump200JSONResponse{Message: "dump stored"}, nil
+}
+
+// RestoreDump implementation.
+// Uploaded dump is saved to temporary file, tables are replaced only if
+// it is read completely. Only single file dumps are accepted.
+func (h *handler) RestoreDump(ctx context.Context, request server.RestoreDumpRequestObject) (server.RestoreDumpResponseObject, error) {
+	path, err := saveUpload(request.Body)
+	if err != nil {
+		return server.RestoreDumpdefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: http.StatusInternalServerError}, nil
+	}
+	defer os.Remove(path)
+	// Manifest is rejected, it could name any file of temporary directory
+	if err := h.db.FromDumpFile(path); err != nil {
+		status := http.StatusInternalServerError
+		var corrupted *errs.ErrCorruptedDump
+		var checksum *errs.ErrDumpChecksum
+		if errors.As(err, &corrupted) || errors.As(err, &checksum) {
+			status = http.StatusBadRequest
+		}
+		return server.RestoreDumpdefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: status}, nil
+	}
+	return server.RestoreDump200JSONResponse{Message: "dump restored"}, nil
+}
+
+// DumpStatus implementation.
+func (h *handler) DumpStatus(ctx context.Context, request server.DumpStatusRequestObject) (server.DumpStatusResponseObject, error) {
+	status, err := h.db.DumpStatus()
+	if err != nil {
+		return server.DumpStatusdefaultJSONResponse{Body: server.Error{Message: err.Error()}, StatusCode: http.StatusInternalServerError}, nil
+	}
+	res := server.DumpStatus{Failures: int64(status.Failures)}
+	if status.Last != nil {
+		res.LastDump = &status.Last.Time
+		res.Size = &status.Last.Size
+		res.Tables = &status.Last.Tables
+	}
+	if status.Failures != 0 {
+		res.LastError = &status.LastError
+		res.LastErrorTime = &status.LastErrorTime
+	}
+	return server.DumpStatus200JSONResponse(res), nil
+}
+
+func saveUpload(r io.Reader) (string, error) {
+	f, err := os.CreateTemp("", "frog-db-upload-*")
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// Check table exists before response starts streaming
+func (h *handler) checkTable(name string) error {
+	dbSchema, err := h.db.IntrospectSchema()
+	if err != nil {
+		return err
+	}
+	if _, ok := dbSchema[name]; !ok {
+		return errs.NewErrTableNotFound(name)
+	}
+	return nil
+}
+
+// Reader of data written by write in background. Response closes
+// reader, so write fails if client is gone.
+func stream(write func(w io.Writer) error) io.ReadCloser {
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(write(writer))
+	}()
+	return reader
+}
+
+func importErrorMessage(res *db.ImportResult, err error) string {
+	if res == nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("%s, inserted %d %s before failure", err.Error(), res.Inserted, english.PluralWord(int(res.Inserted), "row", ""))
+}
+
+func toImportResult(res *db.ImportResult) server.ImportResult {
+	lineErrors := make([]server.LineError, len(res.Errors))
+	for i, lineErr := range res.Errors {
+		lineErrors[i] = server.LineError{Line: lineErr.Line, Message: lineErr.Message}
+	}
+	return server.ImportResult{Inserted: int(res.Inserted), Skipped: int(res.Skipped), Errors: lineErrors}
+}
+
 // DbSchema implementation.
 func (h *handler) DbSchema(ctx context.Context, request server.DbSchemaRequestObject) (server.DbSchemaResponseObject, error) {
 	schema, err := h.db.IntrospectSchema()
@@ -181,6 +439,13 @@ func RowToColumnSet(row server.Row) table.ColumnSet {
 	}
 	return res
 }
+func ConditionsToColumnSet(conditions server.Conditions) table.ColumnSet {
+	res := table.ColumnSet{}
+	for k, v := range conditions {
+		res[k] = v
+	}
+	return res
+}
 func ColumnSetToRows(row table.ColumnSet) server.Row {
 	res := server.Row{}
 	for k, v := range row {
@@ -0,0 +1,144 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ssyrota/frog-db/src/core/db"
+	"github.com/ssyrota/frog-db/src/core/db/dbtypes"
+	"github.com/ssyrota/frog-db/src/core/db/schema"
+	"github.com/ssyrota/frog-db/src/core/db/table"
+	errs "github.com/ssyrota/frog-db/src/core/err"
+	"github.com/ssyrota/frog-db/src/web/server"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test statuses of rows of unexpected version.
+func TestVersionConflict(t *testing.T) {
+	newHandler := func(t *testing.T) *handler {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		database, err := db.New(ctx, filepath.Join(t.TempDir(), "dump.json"), time.Hour)
+		assert.NoError(t, err)
+		_, err = database.Execute(&db.CommandCreateTable{Name: "frog", Schema: schema.T{"id": dbtypes.Integer}})
+		assert.NoError(t, err)
+		_, err = database.Execute(&db.CommandInsert{To: "frog", Data: &[]table.ColumnSet{{"id": 1}}})
+		assert.NoError(t, err)
+		return &handler{database, newTransactions(), 0}
+	}
+	stale := int64(2)
+
+	t.Run("update fails with precondition failed", func(t *testing.T) {
+		h := newHandler(t)
+		res, err := h.UpdateRows(context.Background(), server.UpdateRowsRequestObject{
+			Name:   "frog",
+			Params: server.UpdateRowsParams{Version: &stale},
+			Body:   &server.UpdateRowsJSONRequestBody{Conditions: server.Conditions{"id": 1}, Data: server.Row{"id": 2}}})
+		assert.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		assert.NoError(t, res.VisitUpdateRowsResponse(recorder))
+		assert.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "row version conflict")
+	})
+	t.Run("delete fails with precondition failed", func(t *testing.T) {
+		h := newHandler(t)
+		res, err := h.DeleteRows(context.Background(), server.DeleteRowsRequestObject{
+			Name:   "frog",
+			Params: server.DeleteRowsParams{Version: &stale},
+			Body:   &server.DeleteRowsJSONRequestBody{"id": 1}})
+		assert.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		assert.NoError(t, res.VisitDeleteRowsResponse(recorder))
+		assert.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+	})
+	t.Run("batch fails with precondition failed", func(t *testing.T) {
+		h := newHandler(t)
+		res, err := h.ExecuteBatch(context.Background(), server.ExecuteBatchRequestObject{
+			Body: &server.ExecuteBatchJSONRequestBody{Commands: []server.Command{
+				{Type: server.Delete, Table: "frog", Conditions: &server.Conditions{"id": 1}, Version: &stale}}}})
+		assert.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		assert.NoError(t, res.VisitExecuteBatchResponse(recorder))
+		assert.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+	})
+	t.Run("other failures are conflicts", func(t *testing.T) {
+		h := newHandler(t)
+		res, err := h.DeleteRows(context.Background(), server.DeleteRowsRequestObject{
+			Name: "toad",
+			Body: &server.DeleteRowsJSONRequestBody{}})
+		assert.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		assert.NoError(t, res.VisitDeleteRowsResponse(recorder))
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+	})
+}
+
+// Test that uploaded dump can't be a manifest.
+func TestRestoreDump(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	dumpPath := filepath.Join(t.TempDir(), "dump.json")
+	database, err := db.New(ctx, dumpPath, time.Hour)
+	assert.NoError(t, err)
+	_, err = database.Execute(&db.CommandCreateTable{Name: "frog", Schema: schema.T{"id": dbtypes.Integer}})
+	assert.NoError(t, err)
+	assert.NoError(t, database.StoreDump())
+	manifest, err := os.ReadFile(dumpPath)
+	assert.NoError(t, err)
+	h := &handler{database, newTransactions(), 0}
+
+	res, err := h.RestoreDump(context.Background(), server.RestoreDumpRequestObject{Body: bytes.NewReader(manifest)})
+	assert.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	assert.NoError(t, res.VisitRestoreDumpResponse(recorder))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "manifest is not accepted")
+}
+
+// Test that idle transactions are rolled back.
+func TestIdleTransactions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	database, err := db.New(ctx, filepath.Join(t.TempDir(), "dump.json"), time.Hour)
+	assert.NoError(t, err)
+	begin := func(t *testing.T, txs *transactions, idle bool) (string, *db.Tx) {
+		tx := database.Begin()
+		id, err := txs.add(tx)
+		assert.NoError(t, err)
+		if idle {
+			txs.txs[id].lastUsed = time.Now().Add(-txIdleTimeout - time.Second)
+		}
+		return id, tx
+	}
+
+	t.Run("sweep rolls back only idle ones", func(t *testing.T) {
+		txs := newTransactions()
+		idleID, idleTx := begin(t, txs, true)
+		activeID, activeTx := begin(t, txs, false)
+		assert.NoError(t, txs.sweep(context.Background()))
+		_, err := txs.get(idleID)
+		assert.IsType(t, &errs.ErrTxNotFound{}, err)
+		assert.IsType(t, &errs.ErrTxDone{}, idleTx.Commit())
+		got, err := txs.get(activeID)
+		assert.NoError(t, err)
+		assert.Equal(t, activeTx, got)
+	})
+	t.Run("idle one is not found", func(t *testing.T) {
+		txs := newTransactions()
+		gotID, gotTx := begin(t, txs, true)
+		removedID, removedTx := begin(t, txs, true)
+		_, err := txs.get(gotID)
+		assert.IsType(t, &errs.ErrTxNotFound{}, err)
+		assert.IsType(t, &errs.ErrTxDone{}, gotTx.Commit())
+		_, err = txs.remove(removedID)
+		assert.IsType(t, &errs.ErrTxNotFound{}, err)
+		assert.IsType(t, &errs.ErrTxDone{}, removedTx.Commit())
+		assert.Empty(t, txs.txs)
+	})
+}